@@ -0,0 +1,196 @@
+// Package antilang runs AntiLang programs from Go. An Interpreter keeps the
+// globals of everything it ran, so a host can load a script once and then set
+// values for it, call its functions and read back what it defined.
+//
+//	in := antilang.New()
+//	if _, err := in.Run(`{a; b} add func [ ,a + b return ]`); err != nil {
+//		log.Fatal(err)
+//	}
+//	sum, err := in.Call("add", 1, 2)
+package antilang
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/SirusCodes/anti-lang/src/compiler"
+	"github.com/SirusCodes/anti-lang/src/evaluator"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/module"
+	"github.com/SirusCodes/anti-lang/src/object"
+	"github.com/SirusCodes/anti-lang/src/parser"
+	"github.com/SirusCodes/anti-lang/src/vm"
+)
+
+// Engine is the way an Interpreter executes programs, both give the same
+// results
+type Engine int
+
+const (
+	TreeWalker Engine = iota // evaluates the syntax tree directly
+	VM                       // compiles to bytecode and runs it on a stack machine
+)
+
+// Interpreter runs programs in a single global environment. It is not safe
+// for concurrent use, create one per goroutine instead.
+type Interpreter struct {
+	engine   Engine
+	env      *object.Environment
+	loader   *module.Loader
+	streams  *object.Streams
+	builtins map[string]*object.Builtin // only for this interpreter, see Register
+
+	// file and source of the last run, errors raised later by functions it
+	// defined point into them
+	file   string
+	source string
+}
+
+// New returns an interpreter using the tree walker and the standard streams
+// of the process
+func New() *Interpreter {
+	return NewWithEngine(TreeWalker)
+}
+
+// NewWithEngine returns an interpreter executing programs with engine
+func NewWithEngine(engine Engine) *Interpreter {
+	in := &Interpreter{
+		engine:   engine,
+		env:      object.NewEnvironment(),
+		streams:  &object.Streams{Stdin: bufio.NewReader(os.Stdin), Stdout: os.Stdout, Stderr: os.Stderr},
+		builtins: map[string]*object.Builtin{},
+	}
+	in.env.SetStreams(in.streams)
+	in.env.SetBuiltins(in.builtins)
+
+	exec := evaluator.Eval
+	if engine == VM {
+		exec = vm.Exec
+	}
+	in.loader = module.NewLoader(exec)
+	in.loader.Main("", in.env)
+
+	return in
+}
+
+// SetStdin sets where input reads lines from
+func (in *Interpreter) SetStdin(r io.Reader) {
+	in.streams.Stdin = bufio.NewReader(r)
+}
+
+// SetStdout sets where print writes to
+func (in *Interpreter) SetStdout(w io.Writer) {
+	in.streams.Stdout = w
+}
+
+// SetStderr sets where eprint writes to
+func (in *Interpreter) SetStderr(w io.Writer) {
+	in.streams.Stderr = w
+}
+
+// SetCheckedArithmetic makes integer overflow an error instead of letting the
+// value grow into a big integer, it is off by default
+func (in *Interpreter) SetCheckedArithmetic(on bool) {
+	in.env.SetCheckedArithmetic(on)
+}
+
+// Run runs src and returns the value of its last statement, nil when there is
+// none. Imports resolve from the working directory.
+func (in *Interpreter) Run(src string) (object.Object, error) {
+	in.loader.Main("", in.env)
+	return in.run("", src)
+}
+
+// RunFile runs the file at path, imports made by it resolve relative to it
+func (in *Interpreter) RunFile(path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	in.loader.Main(path, in.env)
+	return in.run(path, string(src))
+}
+
+func (in *Interpreter) run(file, src string) (result object.Object, err error) {
+	defer recoverPanic(&err)
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		return nil, &SyntaxError{File: file, Diagnostics: p.Diagnostics()}
+	}
+
+	in.file, in.source = file, src
+
+	if in.engine == VM {
+		result = vm.Exec(program, in.env)
+	} else {
+		result = evaluator.Eval(program, in.env)
+	}
+
+	return in.result(result)
+}
+
+// Call calls the function or builtin called name with args, which are
+// converted with ToObject
+func (in *Interpreter) Call(name string, args ...any) (result object.Object, err error) {
+	fn, ok := in.env.Get(name)
+	if !ok {
+		builtin, ok := evaluator.LookupBuiltin(name, in.env)
+		if !ok {
+			return nil, fmt.Errorf("antilang: %s is not defined", name)
+		}
+		fn = builtin
+	}
+
+	objects := make([]object.Object, len(args))
+	for i, arg := range args {
+		if objects[i], err = ToObject(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	defer recoverPanic(&err)
+
+	if in.engine == VM {
+		result = vm.New(compiler.New().Bytecode(), in.env).Call(fn, objects...)
+	} else {
+		result = evaluator.Call(fn, objects, in.env)
+	}
+
+	return in.result(result)
+}
+
+// Set defines the global name as value, converted with ToObject
+func (in *Interpreter) Set(name string, value any) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+
+	in.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the global called name, FromObject turns it into a Go value
+func (in *Interpreter) Get(name string) (object.Object, bool) {
+	return in.env.Get(name)
+}
+
+func (in *Interpreter) result(result object.Object) (object.Object, error) {
+	if err, ok := result.(*object.Error); ok {
+		return nil, &Error{Err: err, File: in.file, Source: in.source}
+	}
+	return result, nil
+}
+
+// recoverPanic turns a panic of the interpreter into an error, so a bug in it
+// does not take the host down
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("antilang: internal error: %v", r)
+	}
+}
@@ -0,0 +1,376 @@
+package antilang_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/antilang"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+func forEachEngine(t *testing.T, test func(t *testing.T, in *antilang.Interpreter)) {
+	t.Helper()
+
+	engines := map[string]antilang.Engine{"tree": antilang.TreeWalker, "vm": antilang.VM}
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			test(t, antilang.NewWithEngine(engine))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		result, err := in.Run(",1 + 2 * 3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Inspect() != "7" {
+			t.Errorf("expected 7, got %s", result.Inspect())
+		}
+
+		// globals stay for the next run
+		if _, err := in.Run(",10 = x let"); err != nil {
+			t.Fatal(err)
+		}
+		result, err = in.Run(",x * 2")
+		if err != nil || result.Inspect() != "20" {
+			t.Errorf("expected 20, got %v (%v)", result, err)
+		}
+	})
+}
+
+func TestSetGet(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		if err := in.Set("names", []string{"a", "b"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := in.Run(",{names}len = n let"); err != nil {
+			t.Fatal(err)
+		}
+
+		n, ok := in.Get("n")
+		if !ok || antilang.FromObject(n) != int64(2) {
+			t.Errorf("expected n to be 2, got %v", n)
+		}
+
+		if _, ok := in.Get("missing"); ok {
+			t.Errorf("expected missing to be undefined")
+		}
+
+		if err := in.Set("f", func() {}); err == nil {
+			t.Errorf("expected an error setting a Go func")
+		}
+	})
+}
+
+func TestCall(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		_, err := in.Run(`
+			{a; b} add func [ ,a + b return ]
+			{x} fail func [ ,{$no $ + x}raise ]
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := in.Call("add", 1, 2)
+		if err != nil || result.Inspect() != "3" {
+			t.Errorf("expected 3, got %v (%v)", result, err)
+		}
+
+		result, err = in.Call("len", "añb")
+		if err != nil || result.Inspect() != "3" {
+			t.Errorf("expected builtin len to give 3, got %v (%v)", result, err)
+		}
+
+		_, err = in.Call("fail", "way")
+		var runtime *antilang.Error
+		if !errors.As(err, &runtime) || runtime.Err.Message != "no way" {
+			t.Errorf("expected a runtime error, got %v", err)
+		}
+
+		if _, err := in.Call("nope"); err == nil || err.Error() != "antilang: nope is not defined" {
+			t.Errorf("expected an undefined error, got %v", err)
+		}
+	})
+}
+
+func TestStreams(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		var stdout, stderr bytes.Buffer
+		in.SetStdout(&stdout)
+		in.SetStderr(&stderr)
+		in.SetStdin(strings.NewReader("Ada\r\nlast"))
+
+		_, err := in.Run(`
+			,{$name? $}input = name let
+			,{$hi $ + name}print
+			,{$oops$}eprint
+			,{}input = rest let
+			,{}input = done let
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if stdout.String() != "name? hi Ada\n" {
+			t.Errorf("wrong stdout %q", stdout.String())
+		}
+		if stderr.String() != "oops\n" {
+			t.Errorf("wrong stderr %q", stderr.String())
+		}
+
+		rest, _ := in.Get("rest")
+		done, _ := in.Get("done")
+		if antilang.FromObject(rest) != "last" || antilang.FromObject(done) != nil {
+			t.Errorf("expected last and null, got %s and %s", rest.Inspect(), done.Inspect())
+		}
+	})
+}
+
+// TestSeparateStreams checks that two interpreters do not share their output
+func TestSeparateStreams(t *testing.T) {
+	var first, second bytes.Buffer
+
+	a, b := antilang.New(), antilang.NewWithEngine(antilang.VM)
+	a.SetStdout(&first)
+	b.SetStdout(&second)
+
+	a.Run(",{1}print")
+	b.Run(",{2}print")
+
+	if first.String() != "1\n" || second.String() != "2\n" {
+		t.Errorf("expected 1 and 2, got %q and %q", first.String(), second.String())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		_, err := in.Run(",1 = let")
+		var syntax *antilang.SyntaxError
+		if !errors.As(err, &syntax) || len(syntax.Diagnostics) == 0 || !strings.HasPrefix(err.Error(), "1:6:") {
+			t.Errorf("expected a syntax error, got %v", err)
+		}
+
+		_, err = in.Run(",1 = x let\n,x - $a$")
+		var runtime *antilang.Error
+		if !errors.As(err, &runtime) {
+			t.Fatalf("expected a runtime error, got %v", err)
+		}
+		if err.Error() != "2:4: type mismatch: INTEGER - STRING" {
+			t.Errorf("wrong message %q", err.Error())
+		}
+		if !strings.Contains(runtime.Traceback(), ",x - $a$\n") {
+			t.Errorf("expected the traceback to show the line, got\n%s", runtime.Traceback())
+		}
+	})
+}
+
+func TestRunFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("lib.al", "{x} double func [ ,x * 2 return ]")
+	main := write("main.al", ",$lib.al$ import\n,{21}lib.double = answer let\n,{}boom\n")
+
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		_, err := in.RunFile(main)
+
+		answer, _ := in.Get("answer")
+		if antilang.FromObject(answer) != int64(42) {
+			t.Errorf("expected answer to be 42, got %v", answer)
+		}
+
+		var runtime *antilang.Error
+		if !errors.As(err, &runtime) || runtime.File != main || !strings.HasPrefix(err.Error(), main+":3:") {
+			t.Errorf("expected an error in %s, got %v", main, err)
+		}
+	})
+}
+
+// TestRunFileTwice checks that a file run before can be imported by the next
+func TestRunFileTwice(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	utils := write("utils.al", "{x} double func [ ,x * 2 return ]")
+	main := write("main.al", ",$utils.al$ import\n,{21}utils.double = answer let")
+
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		if _, err := in.RunFile(utils); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := in.RunFile(main); err != nil {
+			t.Fatal(err)
+		}
+
+		answer, _ := in.Get("answer")
+		if antilang.FromObject(answer) != int64(42) {
+			t.Errorf("expected answer to be 42, got %v", answer)
+		}
+
+		// the file being run still cannot import itself
+		self := write("self.al", ",$self.al$ import")
+		if _, err := in.RunFile(self); err == nil || !strings.Contains(err.Error(), "import cycle: self.al -> self.al") {
+			t.Errorf("expected an import cycle, got %v", err)
+		}
+	})
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("lib.al", "{x} inc func [ ,x + 1 return ]")
+	main := write("main.al", ",$lib.al$ import\n,{9223372036854775807}lib.inc")
+
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		result, err := in.Run(",9223372036854775807 + 1")
+		if err != nil || result.Inspect() != "9223372036854775808" {
+			t.Errorf("expected the sum to grow, got %v (%v)", result, err)
+		}
+
+		in.SetCheckedArithmetic(true)
+
+		_, err = in.Run(",9223372036854775807 + 1")
+		var runtime *antilang.Error
+		if !errors.As(err, &runtime) || runtime.Err.Message != "integer overflow: 9223372036854775807 + 1" {
+			t.Errorf("expected an overflow error, got %v", err)
+		}
+
+		// modules follow the interpreter importing them
+		_, err = in.RunFile(main)
+		if !errors.As(err, &runtime) || runtime.Err.Message != "integer overflow: 9223372036854775807 + 1" {
+			t.Errorf("expected an overflow error from the module, got %v", err)
+		}
+	})
+
+	// other interpreters are not affected
+	result, err := antilang.New().Run(",9223372036854775807 + 1")
+	if err != nil || result.Inspect() != "9223372036854775808" {
+		t.Errorf("expected the sum to grow, got %v (%v)", result, err)
+	}
+}
+
+func TestToObject(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	type celsius float32
+	number := 5
+
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{int8(-3), "-3"},
+		{uint64(1 << 63), "9223372036854775808"},
+		{huge, "123456789012345678901234567890"},
+		{big.NewInt(7), "7"},
+		{big.NewRat(5, 4), "1.25"},
+		{big.NewRat(-6, 2), "-3"},
+		{big.NewRat(1, 3), "0.3333333333333333"},
+		{celsius(1.5), "1.5"},
+		{"hi", "hi"},
+		{&number, "5"},
+		{(*int)(nil), "null"},
+		{[]any{1, "a", nil}, "(1; a; null)"},
+		{[2]bool{true, false}, "(true; false)"},
+		{map[string]int{"b": 2, "a": 1}, "[a: 1; b: 2]"},
+		{&object.Integer{Value: 9}, "9"},
+	}
+
+	for _, tt := range tests {
+		obj, err := antilang.ToObject(tt.input)
+		if err != nil {
+			t.Errorf("%#v: unexpected error %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%#v: expected=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	// a rational comes back as it went in
+	rat := big.NewRat(-3, 8)
+	obj, err := antilang.ToObject(rat)
+	if err != nil || obj.Type() != object.DECIMAL_OBJ {
+		t.Fatalf("expected a DECIMAL, got %v (%v)", obj, err)
+	}
+	if back, ok := antilang.FromObject(obj).(*big.Rat); !ok || back.Cmp(rat) != 0 {
+		t.Errorf("expected %v back, got %v", rat, antilang.FromObject(obj))
+	}
+
+	for _, input := range []any{struct{}{}, make(chan int), map[[1]int]int{{1}: 1}} {
+		if _, err := antilang.ToObject(input); err == nil {
+			t.Errorf("%T: expected an error", input)
+		}
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	in := antilang.New()
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{",true", true},
+		{",42", int64(42)},
+		{",2.5", 2.5},
+		{",1.25d", big.NewRat(5, 4)},
+		{",$hi$", "hi"},
+		{",(1; $a$; (false))", []any{int64(1), "a", []any{false}}},
+		{",[$a$ = 1; $b$ = (2)]", map[string]any{"a": int64(1), "b": []any{int64(2)}}},
+		{",[1 = $one$; $two$ = 2]", map[any]any{int64(1): "one", "two": int64(2)}},
+	}
+
+	for _, tt := range tests {
+		obj, err := in.Run(tt.input)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.input, err)
+		}
+
+		got := antilang.FromObject(obj)
+		if rat, ok := tt.expected.(*big.Rat); ok {
+			if got, ok := got.(*big.Rat); !ok || got.Cmp(rat) != 0 {
+				t.Errorf("%s: expected=%v, got=%v", tt.input, rat, got)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+
+	obj, _ := in.Run(",{x} func [ ,x return ]")
+	if _, ok := antilang.FromObject(obj).(object.Object); !ok {
+		t.Errorf("expected a function to stay an object, got %T", antilang.FromObject(obj))
+	}
+}
@@ -0,0 +1,311 @@
+package antilang
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/SirusCodes/anti-lang/src/evaluator"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType  = reflect.TypeOf((*big.Int)(nil))
+	ratType     = reflect.TypeOf((*big.Rat)(nil))
+	contextType = reflect.TypeOf((*object.CallContext)(nil))
+)
+
+// Register makes the Go function fn callable as name from every program, see
+// Builtin for the functions it accepts
+func Register(name string, fn any) error {
+	builtin, err := Builtin(name, fn)
+	if err != nil {
+		return err
+	}
+
+	evaluator.RegisterBuiltin(name, builtin.Fn)
+	return nil
+}
+
+// Register makes the Go function fn callable as name from the programs run
+// by in, see Builtin for the functions it accepts. It goes before builtins
+// registered for every program.
+func (in *Interpreter) Register(name string, fn any) error {
+	builtin, err := Builtin(name, fn)
+	if err != nil {
+		return err
+	}
+
+	in.builtins[name] = builtin
+	return nil
+}
+
+// Builtin wraps the Go function fn as a builtin called name. An
+// object.BuiltinFunction is used as it is, any other function gets its
+// arguments converted from AntiLang values and its result converted back
+// with ToObject:
+//
+//	func(id int64) (map[string]any, error)
+//	func(words ...string) string
+//
+// Parameters may be bools, integers, floats, strings, *big.Int, *big.Rat,
+// slices and maps of those, any or object.Object. A leading
+// *object.CallContext parameter gets the context of the call. Results are
+// nothing, a value, an error or a value and an error, a non-nil error fails
+// the call with its message, and so does a panic.
+func Builtin(name string, fn any) (*object.Builtin, error) {
+	if fn, ok := fn.(func(*object.CallContext, ...object.Object) object.Object); ok {
+		return &object.Builtin{Fn: recovered(name, fn)}, nil
+	}
+	if fn, ok := fn.(object.BuiltinFunction); ok {
+		return &object.Builtin{Fn: recovered(name, fn)}, nil
+	}
+
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("antilang: builtin %s has to be a function, got %T", name, fn)
+	}
+
+	t := v.Type()
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("antilang: builtin %s returns more than a value and an error", name)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("antilang: second result of builtin %s has to be an error", name)
+	}
+
+	withContext := t.NumIn() > 0 && t.In(0) == contextType
+
+	return &object.Builtin{Fn: recovered(name, func(ctx *object.CallContext, args ...object.Object) object.Object {
+		in, err := arguments(name, t, withContext, args)
+		if err != nil {
+			return err
+		}
+
+		if withContext {
+			in = append([]reflect.Value{reflect.ValueOf(ctx)}, in...)
+		}
+
+		return results(name, t, v.Call(in))
+	})}, nil
+}
+
+// recovered turns a panic of fn into an error the program can catch, instead
+// of taking the host down with it
+func recovered(name string, fn object.BuiltinFunction) object.BuiltinFunction {
+	return func(ctx *object.CallContext, args ...object.Object) (result object.Object) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = evaluator.NewError("`%s` panicked: %v", name, r)
+			}
+		}()
+		return fn(ctx, args...)
+	}
+}
+
+// arguments converts args to the parameters of the function of type t
+func arguments(name string, t reflect.Type, withContext bool, args []object.Object) ([]reflect.Value, *object.Error) {
+	params := t.NumIn()
+	first := 0
+	if withContext {
+		first = 1
+	}
+
+	want := params - first
+	if t.IsVariadic() {
+		if len(args) < want-1 {
+			return nil, evaluator.NewKindError(evaluator.ArgumentError, "wrong number of arguments. got=%d, want at least %d", len(args), want-1)
+		}
+	} else if len(args) != want {
+		return nil, evaluator.NewKindError(evaluator.ArgumentError, "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		param := first + i
+		var typ reflect.Type
+		if t.IsVariadic() && param >= params-1 {
+			typ = t.In(params - 1).Elem()
+		} else {
+			typ = t.In(param)
+		}
+
+		value, ok := convert(arg, typ)
+		if !ok {
+			return nil, evaluator.NewKindError(evaluator.TypeError, "argument %d to `%s` must be %s, got %s", i+1, name, typeName(typ), arg.Type())
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// convert turns obj into a value of typ, or reports that it does not fit
+func convert(obj object.Object, typ reflect.Type) (reflect.Value, bool) {
+	if typ.Kind() == reflect.Interface {
+		// object.Object and the like get the value itself, any a Go value
+		if typ.NumMethod() > 0 {
+			if reflect.TypeOf(obj).Implements(typ) {
+				return reflect.ValueOf(obj), true
+			}
+			return reflect.Value{}, false
+		}
+
+		if value := FromObject(obj); value != nil {
+			return reflect.ValueOf(value), true
+		}
+		return reflect.Zero(typ), true
+	}
+
+	if reflect.TypeOf(obj).AssignableTo(typ) {
+		return reflect.ValueOf(obj), true
+	}
+
+	value := reflect.New(typ).Elem()
+
+	switch obj := obj.(type) {
+	case *object.Null:
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			return value, true
+		}
+	case *object.Boolean:
+		if typ.Kind() == reflect.Bool {
+			value.SetBool(obj.Value)
+			return value, true
+		}
+	case *object.Integer:
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !value.OverflowInt(obj.Value) {
+				value.SetInt(obj.Value)
+				return value, true
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if obj.Value >= 0 && !value.OverflowUint(uint64(obj.Value)) {
+				value.SetUint(uint64(obj.Value))
+				return value, true
+			}
+		case reflect.Float32, reflect.Float64:
+			value.SetFloat(float64(obj.Value))
+			return value, true
+		}
+		if typ == bigIntType {
+			return reflect.ValueOf(big.NewInt(obj.Value)), true
+		}
+		if typ == ratType {
+			return reflect.ValueOf(new(big.Rat).SetInt64(obj.Value)), true
+		}
+	case *object.BigInt:
+		if typ == bigIntType {
+			return reflect.ValueOf(new(big.Int).Set(obj.Value)), true
+		}
+		if typ == ratType {
+			return reflect.ValueOf(new(big.Rat).SetInt(obj.Value)), true
+		}
+	case *object.Decimal:
+		if typ == ratType {
+			return reflect.ValueOf(FromObject(obj)), true
+		}
+	case *object.Float:
+		switch typ.Kind() {
+		case reflect.Float32, reflect.Float64:
+			value.SetFloat(obj.Value)
+			return value, true
+		}
+	case *object.String:
+		if typ.Kind() == reflect.String {
+			value.SetString(obj.Value)
+			return value, true
+		}
+	case *object.Array:
+		if typ.Kind() != reflect.Slice {
+			break
+		}
+
+		value = reflect.MakeSlice(typ, len(obj.Elements), len(obj.Elements))
+		for i, element := range obj.Elements {
+			converted, ok := convert(element, typ.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			value.Index(i).Set(converted)
+		}
+		return value, true
+	case *object.Hash:
+		if typ.Kind() != reflect.Map {
+			break
+		}
+
+		value = reflect.MakeMapWithSize(typ, len(obj.Keys))
+		for _, pair := range obj.Ordered() {
+			key, ok := convert(pair.Key, typ.Key())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			element, ok := convert(pair.Value, typ.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			value.SetMapIndex(key, element)
+		}
+		return value, true
+	}
+
+	return reflect.Value{}, false
+}
+
+// typeName names the AntiLang type a parameter of typ takes
+func typeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return string(object.BOOLEAN_OBJ)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if bits := typ.Bits(); bits < 64 {
+			return fmt.Sprintf("%s from %d to %d", object.INTEGER_OBJ, -1<<(bits-1), 1<<(bits-1)-1)
+		}
+		return string(object.INTEGER_OBJ)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if bits := typ.Bits(); bits < 64 {
+			return fmt.Sprintf("%s from 0 to %d", object.INTEGER_OBJ, 1<<bits-1)
+		}
+		return fmt.Sprintf("%s from 0", object.INTEGER_OBJ)
+	case reflect.Float32, reflect.Float64:
+		return string(object.FLOAT_OBJ)
+	case reflect.String:
+		return string(object.STRING_OBJ)
+	case reflect.Slice:
+		return string(object.ARRAY_OBJ) + " of " + typeName(typ.Elem())
+	case reflect.Map:
+		return string(object.HASH_OBJ) + " of " + typeName(typ.Key()) + " to " + typeName(typ.Elem())
+	}
+
+	switch typ {
+	case bigIntType:
+		return string(object.INTEGER_OBJ)
+	case ratType:
+		return string(object.DECIMAL_OBJ)
+	}
+	return typ.String()
+}
+
+// results converts what the function of type t returned
+func results(name string, t reflect.Type, out []reflect.Value) object.Object {
+	if t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return evaluator.NULL
+	}
+
+	result, err := ToObject(out[0].Interface())
+	if err != nil {
+		return evaluator.NewError("cannot use the result of `%s`: %s", name, err)
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package antilang_test
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/antilang"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+func TestRegister(t *testing.T) {
+	users := map[int64]string{1: "ada", 2: "grace"}
+
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		register := func(name string, fn any) {
+			t.Helper()
+			if err := in.Register(name, fn); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		register("fetchUser", func(id int64) (map[string]any, error) {
+			name, ok := users[id]
+			if !ok {
+				return nil, errors.New("no such user")
+			}
+			return map[string]any{"id": id, "name": name}, nil
+		})
+		register("joinAll", func(separator string, words ...string) string {
+			return strings.Join(words, separator)
+		})
+		register("sum", func(numbers []float64) float64 {
+			total := 0.0
+			for _, n := range numbers {
+				total += n
+			}
+			return total
+		})
+		register("keys", func(hash map[string]int) []string {
+			keys := []string{}
+			for key := range hash {
+				keys = append(keys, key)
+			}
+			return keys
+		})
+		register("describe", func(value any) string {
+			switch value.(type) {
+			case nil:
+				return "nothing"
+			case int64:
+				return "integer"
+			case []any:
+				return "array"
+			}
+			return "other"
+		})
+		register("kind", func(obj object.Object) string { return string(obj.Type()) })
+		register("square", func(n *big.Int) *big.Int { return new(big.Int).Mul(n, n) })
+		register("half", func(r *big.Rat) *big.Rat { return new(big.Rat).Quo(r, big.NewRat(2, 1)) })
+		register("crash", func(index int) int { return []int{1}[index] })
+		register("small", func(n int8) int8 { return n })
+		register("nothing", func() {})
+		register("apply", func(ctx *object.CallContext, fn object.Object, arg int) object.Object {
+			return ctx.Call(fn, &object.Integer{Value: int64(arg)})
+		})
+		register("raw", object.BuiltinFunction(func(ctx *object.CallContext, args ...object.Object) object.Object {
+			return &object.Integer{Value: int64(len(args))}
+		}))
+		register("rawCrash", object.BuiltinFunction(func(ctx *object.CallContext, args ...object.Object) object.Object {
+			panic("boom")
+		}))
+
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`,{1}fetchUser`, "[id: 1; name: ada]"},
+			{`,{2}fetchUser = user let ,($name$)user`, "grace"},
+			{`,{3}fetchUser`, "ERROR: no such user"},
+			{`try [ ,{3}fetchUser ] {e} catch [ ,e.message ]`, "no such user"},
+			{`,{$-$; $a$; $b$; $c$}joinAll`, "a-b-c"},
+			{`,{$-$}joinAll`, ""},
+			{`,{(1; 2.5; 3)}sum`, "6.5"},
+			{`,{[$a$ = 1]}keys`, "(a)"},
+			{`,{(1)}describe`, "array"},
+			{`,{5}describe`, "integer"},
+			{`,{$s$}kind`, "STRING"},
+			{`,{99999999999999999999}square`, "9999999999999999999800000000000000000001"},
+			{`,{3}square`, "9"},
+			{`,{2.50d}half`, "1.25"},
+			{`,{3}half`, "1.5"},
+			{`,{99999999999999999999}half`, "49999999999999999999.5"},
+			{`,{2.5}half`, "ERROR: argument 1 to `half` must be DECIMAL, got FLOAT"},
+			{`,{0}crash`, "1"},
+			{`,{5}crash`, "ERROR: `crash` panicked: runtime error: index out of range [5] with length 1"},
+			{`try [ ,{5}crash ] {e} catch [ ,$caught$ ]`, "caught"},
+			{`,{100}small`, "100"},
+			{`,{300}small`, "ERROR: argument 1 to `small` must be INTEGER from -128 to 127, got INTEGER"},
+			{`,{}nothing`, "null"},
+			{`,{{x} func [ ,x + 1 return ]; 41}apply`, "42"},
+			{`,{1; 2; 3}raw`, "3"},
+			{`,{}rawCrash`, "ERROR: `rawCrash` panicked: boom"},
+			{`,{$1$}fetchUser`, "ERROR: argument 1 to `fetchUser` must be INTEGER, got STRING"},
+			{`,{(1; $a$)}sum`, "ERROR: argument 1 to `sum` must be ARRAY of FLOAT, got ARRAY"},
+			{`,{}fetchUser`, "ERROR: wrong number of arguments. got=0, want=1"},
+			{`,{}joinAll`, "ERROR: wrong number of arguments. got=0, want at least 1"},
+		}
+
+		for _, tt := range tests {
+			var got string
+			result, err := in.Run(tt.input)
+			if err != nil {
+				var runtime *antilang.Error
+				if !errors.As(err, &runtime) {
+					t.Errorf("%s: unexpected error %s", tt.input, err)
+					continue
+				}
+				got = runtime.Err.Inspect()
+			} else {
+				got = result.Inspect()
+			}
+
+			if got != tt.expected {
+				t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
+}
+
+func TestRegisterScope(t *testing.T) {
+	if err := antilang.Register("hostGreeting", func() string { return "hello" }); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.al")
+	if err := os.WriteFile(lib, []byte(",{}hostGreeting + {}secret = greeting let"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "main.al")
+	if err := os.WriteFile(main, []byte(",$lib.al$ import\n,lib.greeting = greeting let"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	forEachEngine(t, func(t *testing.T, in *antilang.Interpreter) {
+		if err := in.Register("secret", func() string { return " there" }); err != nil {
+			t.Fatal(err)
+		}
+
+		// modules see the builtins of the interpreter importing them
+		if _, err := in.RunFile(main); err != nil {
+			t.Fatal(err)
+		}
+		greeting, _ := in.Get("greeting")
+		if antilang.FromObject(greeting) != "hello there" {
+			t.Errorf("expected hello there, got %v", greeting)
+		}
+
+		// an interpreter's own builtins go before the global ones
+		if err := in.Register("hostGreeting", func() string { return "hi" }); err != nil {
+			t.Fatal(err)
+		}
+		result, err := in.Run(",{}hostGreeting")
+		if err != nil || antilang.FromObject(result) != "hi" {
+			t.Errorf("expected hi, got %v (%v)", result, err)
+		}
+	})
+
+	// and stay with it
+	result, err := antilang.New().Run(",{}hostGreeting")
+	if err != nil || antilang.FromObject(result) != "hello" {
+		t.Errorf("expected hello, got %v (%v)", result, err)
+	}
+	if _, err := antilang.New().Run(",{}secret"); err == nil {
+		t.Errorf("expected secret to be undefined in a new interpreter")
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	in := antilang.New()
+
+	tests := []struct {
+		fn       any
+		expected string
+	}{
+		{42, "antilang: builtin f has to be a function, got int"},
+		{(func())(nil), "antilang: builtin f has to be a function, got func()"},
+		{func() (int, int, error) { return 0, 0, nil }, "antilang: builtin f returns more than a value and an error"},
+		{func() (int, int) { return 0, 0 }, "antilang: second result of builtin f has to be an error"},
+	}
+
+	for _, tt := range tests {
+		if err := in.Register("f", tt.fn); err == nil || err.Error() != tt.expected {
+			t.Errorf("%T: expected=%q, got=%v", tt.fn, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package antilang
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/SirusCodes/anti-lang/src/evaluator"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+// ToObject converts a Go value to the AntiLang value closest to it. Slices and
+// arrays become arrays, maps become hashes with their keys sorted, pointers
+// are followed and an object.Object is returned as it is. A *big.Rat becomes
+// a decimal, rounded like a decimal division when its digits do not end.
+func ToObject(value any) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return evaluator.NULL, nil
+	case object.Object:
+		return value, nil
+	case bool:
+		if value {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case string:
+		return &object.String{Value: value}, nil
+	case *big.Int:
+		return integer(value), nil
+	case *big.Rat:
+		return object.NewDecimalFromInt(value.Num()).Quo(object.NewDecimalFromInt(value.Denom())), nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return integer(new(big.Int).SetUint64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Bool:
+		return ToObject(v.Bool())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return ToObject(v.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return evaluator.NULL, nil
+		}
+
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := ToObject(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		return toHash(v)
+	}
+
+	return nil, fmt.Errorf("antilang: cannot convert %T", value)
+}
+
+// toHash sorts the keys, the order of a Go map would change every run
+func toHash(v reflect.Value) (object.Object, error) {
+	if v.IsNil() {
+		return evaluator.NULL, nil
+	}
+
+	pairs := make([]object.HashPair, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := ToObject(iter.Key().Interface())
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := key.(object.Hashable); !ok {
+			return nil, fmt.Errorf("antilang: cannot use %s as a hash key", key.Type())
+		}
+
+		value, err := ToObject(iter.Value().Interface())
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, object.HashPair{Key: key, Value: value})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
+
+	hash := object.NewHash()
+	for _, pair := range pairs {
+		hash.Set(pair.Key.(object.Hashable).HashKey(), pair)
+	}
+	return hash, nil
+}
+
+func integer(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInt{Value: new(big.Int).Set(value)}
+}
+
+// FromObject converts an AntiLang value to a plain Go value: nil, bool,
+// int64, *big.Int, float64, *big.Rat, string, []any or a map. Hashes with
+// only string keys become map[string]any, any other hash map[any]any. Values
+// without a Go counterpart, like functions, are returned as they are.
+func FromObject(obj object.Object) any {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil
+	case *object.Boolean:
+		return obj.Value
+	case *object.Integer:
+		return obj.Value
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value)
+	case *object.Float:
+		return obj.Value
+	case *object.Decimal:
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(obj.Scale)), nil)
+		return new(big.Rat).SetFrac(obj.Unscaled, scale)
+	case *object.String:
+		return obj.Value
+	case *object.Array:
+		values := make([]any, len(obj.Elements))
+		for i, element := range obj.Elements {
+			values[i] = FromObject(element)
+		}
+		return values
+	case *object.Hash:
+		return fromHash(obj)
+	}
+
+	return obj
+}
+
+func fromHash(hash *object.Hash) any {
+	strings := true
+	for _, pair := range hash.Pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			strings = false
+			break
+		}
+	}
+
+	if strings {
+		values := make(map[string]any, len(hash.Pairs))
+		for _, pair := range hash.Pairs {
+			values[pair.Key.(*object.String).Value] = FromObject(pair.Value)
+		}
+		return values
+	}
+
+	values := make(map[any]any, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		values[FromObject(pair.Key)] = FromObject(pair.Value)
+	}
+	return values
+}
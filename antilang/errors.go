@@ -0,0 +1,53 @@
+package antilang
+
+import (
+	"strings"
+
+	"github.com/SirusCodes/anti-lang/src/object"
+	"github.com/SirusCodes/anti-lang/src/parser"
+)
+
+// SyntaxError is returned when a program does not parse, nothing of it ran
+type SyntaxError struct {
+	File        string // empty for source passed to Run
+	Diagnostics []parser.Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = e.location() + d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *SyntaxError) location() string {
+	if e.File == "" {
+		return ""
+	}
+	return e.File + ":"
+}
+
+// Error is a runtime error the program did not catch
+type Error struct {
+	Err    *object.Error
+	File   string // file and source the error was raised in
+	Source string
+}
+
+func (e *Error) Error() string {
+	if e.Err.Pos.Line == 0 {
+		return e.Err.Message
+	}
+
+	location := e.Err.Pos.String()
+	if e.File != "" {
+		location = e.File + ":" + location
+	}
+	return location + ": " + e.Err.Message
+}
+
+// Traceback renders the error with its call stack and the offending line
+func (e *Error) Traceback() string {
+	return e.Err.Traceback(e.File, e.Source)
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"strings"
 
-	"github.com/SirusCodes/anti-lang/src/evaluator"
-	"github.com/SirusCodes/anti-lang/src/lexer"
-	"github.com/SirusCodes/anti-lang/src/object"
-	"github.com/SirusCodes/anti-lang/src/parser"
+	"github.com/SirusCodes/anti-lang/antilang"
+	"github.com/SirusCodes/anti-lang/src/format"
 	"github.com/SirusCodes/anti-lang/src/repl"
 )
 
@@ -24,12 +26,24 @@ func main() {
 	case "repl":
 		runREPL()
 	case "run":
-		if len(os.Args) < 3 {
+		runCmd := flag.NewFlagSet("run", flag.ExitOnError)
+		engine := runCmd.String("engine", "tree", "execution engine to use: vm or tree")
+		checked := runCmd.Bool("checked", false, "report integer overflow as an error")
+		runCmd.Parse(os.Args[2:])
+
+		if runCmd.NArg() < 1 || (*engine != "vm" && *engine != "tree") {
 			printHelp()
 			return
 		}
-		path := os.Args[2]
-		runFile(path)
+		path := runCmd.Arg(0)
+		os.Exit(runFile(path, *engine, *checked))
+	case "fmt":
+		fmtCmd := flag.NewFlagSet("fmt", flag.ExitOnError)
+		write := fmtCmd.Bool("w", false, "write the result back to the file instead of printing it")
+		diff := fmtCmd.Bool("d", false, "print a diff instead of the formatted source")
+		fmtCmd.Parse(os.Args[2:])
+
+		os.Exit(formatFiles(fmtCmd.Args(), *write, *diff))
 	case "help":
 		printHelp()
 	default:
@@ -48,37 +62,95 @@ func runREPL() {
 	repl.Start(os.Stdin, os.Stdout)
 }
 
-func runFile(path string) int {
-	file, err := os.ReadFile(path)
-	if err != nil {
-		panic(err)
+func runFile(path string, engine string, checked bool) int {
+	in := antilang.New()
+	if engine == "vm" {
+		in = antilang.NewWithEngine(antilang.VM)
 	}
+	in.SetCheckedArithmetic(checked)
 
-	l := lexer.New(string(file))
-	p := parser.New(l)
-	ast := p.ParseProgram()
+	_, err := in.RunFile(path)
+	if err == nil {
+		return 0
+	}
+
+	if runtime, ok := err.(*antilang.Error); ok {
+		fmt.Print(runtime.Traceback())
+	} else {
+		fmt.Println(err)
+	}
+	return 1
+}
+
+// formatFiles formats every file in paths, or stdin when there are none
+func formatFiles(paths []string, write, diff bool) int {
+	if len(paths) == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		out, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, located("<stdin>", err))
+			return 1
+		}
+		os.Stdout.Write(out)
+		return 0
+	}
 
-	if len(p.Errors()) != 0 {
-		for _, msg := range p.Errors() {
-			fmt.Println(msg)
+	code := 0
+	for _, path := range paths {
+		if err := formatFile(path, write, diff); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
 		}
-		return 1
 	}
+	return code
+}
 
-	env := object.NewEnvironment()
-	resp := evaluator.Eval(ast, env)
+func formatFile(path string, write, diff bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
 
-	if resp != nil && resp.Type() == object.ERROR_OBJ {
-		fmt.Println(resp.Inspect())
-		return 1
+	out, err := format.Source(src)
+	if err != nil {
+		return located(path, err)
 	}
 
-	return 0
+	if diff {
+		os.Stdout.Write(format.Diff(path, src, out))
+	}
+
+	if write {
+		if string(out) == string(src) {
+			return nil
+		}
+		return os.WriteFile(path, out, 0o644)
+	}
+
+	if !diff {
+		os.Stdout.Write(out)
+	}
+	return nil
+}
+
+// located puts name in front of every diagnostic of err, one per line
+func located(name string, err error) error {
+	lines := strings.Split(err.Error(), "\n")
+	for i, line := range lines {
+		lines[i] = name + ":" + line
+	}
+	return errors.New(strings.Join(lines, "\n"))
 }
 
 func printHelp() {
 	fmt.Println("Usage: anti-lang [command] [args]")
 	fmt.Println("Commands:")
 	fmt.Println("  repl - Start the AntiLang REPL")
-	fmt.Println("  run [filename] - Run an AntiLang file")
+	fmt.Println("  run [--engine=vm|tree] [--checked] [filename] - Run an AntiLang file")
+	fmt.Println("  fmt [-w] [-d] [filenames] - Format AntiLang files, or stdin without any")
 }
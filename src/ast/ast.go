@@ -2,12 +2,15 @@ package ast
 
 import (
 	"bytes"
+
+	"github.com/SirusCodes/anti-lang/src/lexer"
 )
 
 // Node is the interface that all nodes in the AST implement
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() lexer.Position
 }
 
 // Statement is the interface that all statement nodes in the AST implement
@@ -36,6 +39,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos returns the position of the first statement in the program
+func (p *Program) Pos() lexer.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return lexer.Position{}
+}
+
 // String returns the string representation of the program
 func (p *Program) String() string {
 	var out bytes.Buffer
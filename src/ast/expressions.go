@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/SirusCodes/anti-lang/src/lexer"
@@ -18,6 +20,10 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() lexer.Position {
+	return i.Token.Pos
+}
+
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -34,6 +40,10 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+func (ce *CallExpression) Pos() lexer.Position {
+	return ce.Token.Pos
+}
+
 func (ce *CallExpression) String() string {
 	var args []string
 
@@ -57,6 +67,10 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *InfixExpression) Pos() lexer.Position {
+	return ie.Token.Pos
+}
+
 func (ie *InfixExpression) String() string {
 	return "(" + ie.Left.String() + " " + ie.Operator + " " + ie.Right.String() + ")"
 }
@@ -73,6 +87,10 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe *PrefixExpression) Pos() lexer.Position {
+	return pe.Token.Pos
+}
+
 func (pe *PrefixExpression) String() string {
 	return "(" + pe.Operator + pe.Right.String() + ")"
 }
@@ -90,9 +108,18 @@ func (i *ConditionalExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *ConditionalExpression) Pos() lexer.Position {
+	return i.Token.Pos
+}
+
 func (i *ConditionalExpression) String() string {
 	var out bytes.Buffer
 
+	// the else branch has no condition of its own
+	if i.Condition == nil {
+		return i.ExecutionBlock.String()
+	}
+
 	out.WriteString(i.Condition.String())
 	out.WriteString("if")
 	out.WriteString(" ")
@@ -106,6 +133,35 @@ func (i *ConditionalExpression) String() string {
 	return out.String()
 }
 
+// TryExpression runs Body and falls back to Handler when it fails, with the
+// error bound to Variable: `try [...] {err} catch [...]`
+type TryExpression struct {
+	Expression
+	Token    lexer.Token // the 'try' token
+	Body     *BlockStatement
+	Variable *Identifier // nil when the handler ignores the error
+	Handler  *BlockStatement
+}
+
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) Pos() lexer.Position  { return te.Token.Pos }
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try")
+	out.WriteString(te.Body.String())
+	out.WriteString("{")
+	if te.Variable != nil {
+		out.WriteString(te.Variable.String())
+	}
+	out.WriteString("}")
+	out.WriteString("catch")
+	out.WriteString(te.Handler.String())
+
+	return out.String()
+}
+
 // FunctionExpression represents a function expression
 type FunctionExpression struct {
 	Expression
@@ -118,6 +174,10 @@ func (fe *FunctionExpression) TokenLiteral() string {
 	return fe.Token.Literal
 }
 
+func (fe *FunctionExpression) Pos() lexer.Position {
+	return fe.Token.Pos
+}
+
 func (fe *FunctionExpression) String() string {
 	var out bytes.Buffer
 	var params []string
@@ -136,6 +196,40 @@ func (fe *FunctionExpression) String() string {
 	return out.String()
 }
 
+// FunctionLiteral represents an anonymous function, which evaluates to the
+// function itself instead of binding it to a name
+type FunctionLiteral struct {
+	Expression
+	Token      lexer.Token // the 'func' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (fl *FunctionLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FunctionLiteral) Pos() lexer.Position {
+	return fl.Token.Pos
+}
+
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+	var params []string
+
+	for _, p := range fl.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(params, "; "))
+	out.WriteString("}")
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString(fl.Body.String())
+
+	return out.String()
+}
+
 // WhileExpression represents a while expression
 type WhileExpression struct {
 	Expression
@@ -148,6 +242,10 @@ func (we *WhileExpression) TokenLiteral() string {
 	return we.Token.Literal
 }
 
+func (we *WhileExpression) Pos() lexer.Position {
+	return we.Token.Pos
+}
+
 func (we *WhileExpression) String() string {
 	var out bytes.Buffer
 
@@ -160,11 +258,74 @@ func (we *WhileExpression) String() string {
 	return out.String()
 }
 
+// ForExpression represents a for loop, either over the values of an iterable
+// `{item; arr} for [...]` or over a range `{i; start; end; step} for [...]`
+type ForExpression struct {
+	Expression
+	Token    lexer.Token // the 'for' token
+	Variable *Identifier
+	Iterable Expression // nil for the range form
+	Start    Expression
+	End      Expression
+	Step     Expression // optional
+	Body     *BlockStatement
+}
+
+func (fe *ForExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+
+func (fe *ForExpression) Pos() lexer.Position {
+	return fe.Token.Pos
+}
+
+func (fe *ForExpression) String() string {
+	var out bytes.Buffer
+	args := []string{fe.Variable.String()}
+
+	if fe.Iterable != nil {
+		args = append(args, fe.Iterable.String())
+	} else {
+		args = append(args, fe.Start.String(), fe.End.String())
+		if fe.Step != nil {
+			args = append(args, fe.Step.String())
+		}
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(args, "; "))
+	out.WriteString("}")
+	out.WriteString("for")
+	out.WriteString(fe.Body.String())
+
+	return out.String()
+}
+
+// MemberExpression represents access to a binding of a module, `utils.add`
+type MemberExpression struct {
+	Expression
+	Token    lexer.Token // the '.' token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) Pos() lexer.Position {
+	return me.Token.Pos
+}
+
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Property.String()
+}
+
 // AssignExpression represents an assign expression
 type AssignExpression struct {
 	Expression
 	Token    lexer.Token
-	Name     *Identifier
+	Target   Expression // *Identifier or *IndexExpression
 	Operator string
 	Value    Expression
 }
@@ -173,8 +334,12 @@ func (ae *AssignExpression) TokenLiteral() string {
 	return ae.Token.Literal
 }
 
+func (ae *AssignExpression) Pos() lexer.Position {
+	return ae.Token.Pos
+}
+
 func (ae *AssignExpression) String() string {
-	return ae.Value.String() + " " + ae.Operator + " " + ae.Name.String()
+	return ae.Value.String() + " " + ae.Operator + " " + ae.Target.String()
 }
 
 // IntegerLiteral represents an integer literal
@@ -188,6 +353,10 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+func (il *IntegerLiteral) Pos() lexer.Position {
+	return il.Token.Pos
+}
+
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
@@ -203,10 +372,37 @@ func (fl *FloatLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl *FloatLiteral) Pos() lexer.Position {
+	return fl.Token.Pos
+}
+
 func (fl *FloatLiteral) String() string {
 	return fl.Token.Literal
 }
 
+// BigIntLiteral represents an integer literal too large for an int64
+type BigIntLiteral struct {
+	Expression
+	Token lexer.Token
+	Value *big.Int
+}
+
+func (bl *BigIntLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntLiteral) Pos() lexer.Position  { return bl.Token.Pos }
+func (bl *BigIntLiteral) String() string       { return bl.Token.Literal }
+
+// DecimalLiteral represents an exact decimal like 0.10d, Value is the number
+// without the 'd'
+type DecimalLiteral struct {
+	Expression
+	Token lexer.Token
+	Value string
+}
+
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+func (dl *DecimalLiteral) Pos() lexer.Position  { return dl.Token.Pos }
+func (dl *DecimalLiteral) String() string       { return dl.Token.Literal }
+
 // BooleanLiteral represents a boolean literal
 type BooleanLiteral struct {
 	Expression
@@ -218,6 +414,10 @@ func (b *BooleanLiteral) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BooleanLiteral) Pos() lexer.Position {
+	return b.Token.Pos
+}
+
 func (b *BooleanLiteral) String() string {
 	return b.Token.Literal
 }
@@ -233,6 +433,10 @@ func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
 
+func (sl *StringLiteral) Pos() lexer.Position {
+	return sl.Token.Pos
+}
+
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
@@ -248,6 +452,10 @@ func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
 
+func (al *ArrayLiteral) Pos() lexer.Position {
+	return al.Token.Pos
+}
+
 func (al *ArrayLiteral) String() string {
 	var elements []string
 
@@ -270,6 +478,10 @@ func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IndexExpression) Pos() lexer.Position {
+	return ie.Token.Pos
+}
+
 func (ie *IndexExpression) String() string {
 	return "{" + ie.Array.String() + "(" + ie.Index.String() + ")}"
 }
@@ -285,12 +497,30 @@ func (hl *HashLiteral) TokenLiteral() string {
 	return hl.Token.Literal
 }
 
+func (hl *HashLiteral) Pos() lexer.Position {
+	return hl.Token.Pos
+}
+
 func (hl *HashLiteral) String() string {
 	var pairs []string
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+"="+value.String())
+	for _, key := range hl.OrderedKeys() {
+		pairs = append(pairs, key.String()+"="+hl.Pairs[key].String())
 	}
 
 	return "[" + strings.Join(pairs, "; ") + "]"
 }
+
+// OrderedKeys returns the keys in the order they appear in the source
+func (hl *HashLiteral) OrderedKeys() []Expression {
+	keys := make([]Expression, 0, len(hl.Pairs))
+	for key := range hl.Pairs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Pos().Offset < keys[j].Pos().Offset
+	})
+
+	return keys
+}
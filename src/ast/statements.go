@@ -1,6 +1,11 @@
 package ast
 
-import "github.com/SirusCodes/anti-lang/src/lexer"
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/SirusCodes/anti-lang/src/lexer"
+)
 
 // EXPRESSION statement
 type ExpressionStatement struct {
@@ -10,6 +15,7 @@ type ExpressionStatement struct {
 }
 
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() lexer.Position  { return es.Token.Pos }
 
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -28,6 +34,7 @@ type LetStatement struct {
 }
 
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() lexer.Position  { return ls.Token.Pos }
 
 func (ls *LetStatement) String() string {
 	var out string
@@ -51,6 +58,7 @@ type ReturnStatement struct {
 }
 
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() lexer.Position  { return rs.Token.Pos }
 
 func (rs *ReturnStatement) String() string {
 	var out string
@@ -64,14 +72,66 @@ func (rs *ReturnStatement) String() string {
 	return out
 }
 
+// IMPORT statement
+type ImportStatement struct {
+	Statement
+	Token lexer.Token // the 'import' token
+	Path  *StringLiteral
+	Name  *Identifier // nil when the module is bound under its file name
+}
+
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) Pos() lexer.Position  { return is.Token.Pos }
+
+func (is *ImportStatement) String() string {
+	out := ",$" + is.Path.Value + "$"
+	if is.Name != nil {
+		out += " = " + is.Name.String()
+	}
+	return out + " " + is.TokenLiteral()
+}
+
+// BindingName is the name the module is bound to, the file name without its
+// extension unless the import names it
+func (is *ImportStatement) BindingName() string {
+	if is.Name != nil {
+		return is.Name.Value
+	}
+
+	base := filepath.Base(is.Path.Value)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// BREAK statement
+type BreakStatement struct {
+	Statement
+	Token lexer.Token // the 'break' token
+}
+
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() lexer.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return "," + bs.TokenLiteral() }
+
+// CONTINUE statement
+type ContinueStatement struct {
+	Statement
+	Token lexer.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() lexer.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return "," + cs.TokenLiteral() }
+
 // BLOCK statement
 type BlockStatement struct {
 	Statement
 	Token      lexer.Token // the '[' token
 	Statements []Statement
+	Close      lexer.Token // the ']' token
 }
 
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() lexer.Position  { return bs.Token.Pos }
 
 func (bs *BlockStatement) String() string {
 	var out string
@@ -0,0 +1,99 @@
+package ast
+
+// Inspect calls f for node and then for each of its children in source
+// order, it does not descend into a node when f returns false
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *ExpressionStatement:
+		inspectExpression(n.Expression, f)
+	case *LetStatement:
+		inspectExpression(n.Value, f)
+		Inspect(n.Name, f)
+	case *ReturnStatement:
+		inspectExpression(n.ReturnValue, f)
+	case *ImportStatement:
+		Inspect(n.Path, f)
+		if n.Name != nil {
+			Inspect(n.Name, f)
+		}
+	case *CallExpression:
+		for _, arg := range n.Arguments {
+			inspectExpression(arg, f)
+		}
+		inspectExpression(n.Function, f)
+	case *InfixExpression:
+		inspectExpression(n.Left, f)
+		inspectExpression(n.Right, f)
+	case *PrefixExpression:
+		inspectExpression(n.Right, f)
+	case *ConditionalExpression:
+		inspectExpression(n.Condition, f)
+		Inspect(n.ExecutionBlock, f)
+		if n.NextConditional != nil {
+			Inspect(n.NextConditional, f)
+		}
+	case *TryExpression:
+		Inspect(n.Body, f)
+		if n.Variable != nil {
+			Inspect(n.Variable, f)
+		}
+		Inspect(n.Handler, f)
+	case *FunctionExpression:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		Inspect(n.Body, f)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		Inspect(n.Body, f)
+	case *WhileExpression:
+		inspectExpression(n.Condition, f)
+		Inspect(n.Body, f)
+	case *ForExpression:
+		Inspect(n.Variable, f)
+		for _, e := range []Expression{n.Iterable, n.Start, n.End, n.Step} {
+			inspectExpression(e, f)
+		}
+		Inspect(n.Body, f)
+	case *MemberExpression:
+		inspectExpression(n.Object, f)
+		Inspect(n.Property, f)
+	case *AssignExpression:
+		inspectExpression(n.Value, f)
+		inspectExpression(n.Target, f)
+	case *ArrayLiteral:
+		for _, el := range n.Elements {
+			inspectExpression(el, f)
+		}
+	case *IndexExpression:
+		inspectExpression(n.Index, f)
+		inspectExpression(n.Array, f)
+	case *HashLiteral:
+		for _, key := range n.OrderedKeys() {
+			inspectExpression(key, f)
+			inspectExpression(n.Pairs[key], f)
+		}
+	}
+}
+
+// inspectExpression skips optional expressions that were left out, a nil
+// Expression is not a nil Node once it is converted
+func inspectExpression(exp Expression, f func(Node) bool) {
+	if exp != nil {
+		Inspect(exp, f)
+	}
+}
@@ -0,0 +1,115 @@
+package code
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 0, 0, 255, 254}},
+		{OpConstant, []int{70000}, []byte{byte(OpConstant), 0, 1, 17, 112}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpCall, []int{255}, []byte{byte(OpCall), 0, 255}},
+		{OpIter, []int{3}, []byte{byte(OpIter), 3}},
+		{OpAssignGlobal, []int{1, 2}, []byte{byte(OpAssignGlobal), 0, 0, 0, 1, 0, 0, 0, 2}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		if len(instruction) != len(tt.expected) {
+			t.Errorf("instruction has wrong length. want=%d, got=%d", len(tt.expected), len(instruction))
+		}
+
+		for i, b := range tt.expected {
+			if instruction[i] != b {
+				t.Errorf("wrong byte at pos %d. want=%d, got=%d", i, b, instruction[i])
+			}
+		}
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetGlobal, 1),
+		Make(OpConstant, 65535),
+		Make(OpAssignGlobal, 2, 3),
+		Make(OpCall, 1),
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetGlobal 1
+0006 OpConstant 65535
+0011 OpAssignGlobal 2 3
+0020 OpCall 1
+`
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	if concatted.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q", expected, concatted.String())
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{70000}, 4},
+		{OpCall, []int{255}, 2},
+		{OpIter, []int{3}, 1},
+		{OpAssignGlobal, []int{7, 9}, 8},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		def, err := Lookup(byte(tt.op))
+		if err != nil {
+			t.Fatalf("definition not found: %q\n", err)
+		}
+
+		operandsRead, n := ReadOperands(def, instruction[1:])
+		if n != tt.bytesRead {
+			t.Fatalf("n wrong. want=%d, got=%d", tt.bytesRead, n)
+		}
+
+		for i, want := range tt.operands {
+			if operandsRead[i] != want {
+				t.Errorf("operand wrong. want=%d, got=%d", want, operandsRead[i])
+			}
+		}
+	}
+}
+
+func TestCheckOperands(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected string
+	}{
+		{OpConstant, []int{70000}, ""},
+		{OpCall, []int{65535}, ""},
+		{OpCall, []int{65536}, "operand 65536 of OpCall does not fit in 2 bytes"},
+		{OpIter, []int{256}, "operand 256 of OpIter does not fit in 1 bytes"},
+		{OpJump, []int{-1}, "operand -1 of OpJump does not fit in 4 bytes"},
+	}
+
+	for _, tt := range tests {
+		err := CheckOperands(tt.op, tt.operands...)
+		if tt.expected == "" && err != nil {
+			t.Errorf("%v: unexpected error %s", tt.operands, err)
+		}
+		if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+			t.Errorf("%v: expected=%q, got=%v", tt.operands, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,716 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+var infixOperators = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"//": code.OpIntDiv,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	"<":  code.OpLessThan,
+	"<=": code.OpLessEqual,
+	">":  code.OpGreaterThan,
+	">=": code.OpGreaterEqual,
+}
+
+var shortCircuitOperators = map[string]code.Opcode{
+	"&&": code.OpAnd,
+	"||": code.OpOr,
+	"??": code.OpCoalesce,
+}
+
+var prefixOperators = map[string]code.Opcode{
+	"-": code.OpMinus,
+	"!": code.OpBang,
+}
+
+// Bytecode is the output of the compiler which is executed by the vm
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    map[int]lexer.Position
+	Constants    []object.Object
+	Globals      []string // names of the global variables, bound to slots by the vm
+}
+
+// CompilationScope holds the instructions of a single function body
+type CompilationScope struct {
+	instructions code.Instructions
+	positions    map[int]lexer.Position
+	loops        []*loop // loops around the instruction being compiled, innermost last
+	tries        int     // try blocks around the instruction being compiled
+
+	// slots of the variables of a function, the top level has none and
+	// uses globals instead
+	locals     map[string]int
+	localNames []string
+}
+
+// loop collects the jumps break and continue compile to inside a loop body
+type loop struct {
+	continueTarget int
+	breakJumps     []int
+	tries          int // try blocks around the loop, the ones inside are left on break
+}
+
+type Compiler struct {
+	constants []object.Object
+	names     map[string]int // interned identifier names in the constant pool
+
+	globals     map[string]int
+	globalNames []string
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// err is the first operand too large for its instruction, emit has no
+	// way to return it
+	err error
+}
+
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions: code.Instructions{},
+		positions:    make(map[int]lexer.Position),
+	}
+
+	return &Compiler{
+		constants:  []object.Object{},
+		names:      make(map[string]int),
+		globals:    make(map[string]int),
+		scopes:     []CompilationScope{mainScope},
+		scopeIndex: 0,
+	}
+}
+
+// Compile compiles node into the bytecode returned by Bytecode
+func (c *Compiler) Compile(node ast.Node) error {
+	if err := c.compile(node); err != nil {
+		return err
+	}
+	return c.err
+}
+
+func (c *Compiler) compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		return c.compileStatements(node.Statements)
+	case *ast.ExpressionStatement:
+		// the evaluator treats statements the parser could not make sense of as no-ops
+		if node.Expression == nil {
+			c.emit(node.Pos(), code.OpNull)
+			return nil
+		}
+		return c.compile(node.Expression)
+	case *ast.BlockStatement:
+		if len(node.Statements) == 0 {
+			c.emit(node.Pos(), code.OpNull)
+			return nil
+		}
+		return c.compileStatements(node.Statements)
+	case *ast.LetStatement:
+		if err := c.compile(node.Value); err != nil {
+			return err
+		}
+		c.storeName(node.Pos(), node.Name.Value)
+	case *ast.ImportStatement:
+		c.emit(node.Pos(), code.OpImport, c.addName(node.Path.Value))
+		c.storeName(node.Pos(), node.BindingName())
+		c.emit(node.Pos(), code.OpPop)
+		c.emit(node.Pos(), code.OpNull)
+	case *ast.MemberExpression:
+		if err := c.compile(node.Object); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), code.OpMember, c.addName(node.Property.Value))
+	case *ast.ReturnStatement:
+		if err := c.compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), code.OpReturnValue)
+	case *ast.BreakStatement:
+		l := c.currentLoop()
+		if l == nil {
+			return fmt.Errorf("%s: break outside of loop", node.Pos())
+		}
+		c.leaveTries(node.Pos(), l)
+		l.breakJumps = append(l.breakJumps, c.emit(node.Pos(), code.OpJump, 9999))
+	case *ast.ContinueStatement:
+		l := c.currentLoop()
+		if l == nil {
+			return fmt.Errorf("%s: continue outside of loop", node.Pos())
+		}
+		c.leaveTries(node.Pos(), l)
+		c.emit(node.Pos(), code.OpJump, l.continueTarget)
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(node.Pos(), code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(node.Pos(), code.OpConstant, c.addConstant(float))
+	case *ast.BigIntLiteral:
+		c.emit(node.Pos(), code.OpConstant, c.addConstant(&object.BigInt{Value: node.Value}))
+	case *ast.DecimalLiteral:
+		decimal, err := object.ParseDecimal(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %s", node.Pos(), err)
+		}
+		c.emit(node.Pos(), code.OpConstant, c.addConstant(decimal))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(node.Pos(), code.OpConstant, c.addConstant(str))
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(node.Pos(), code.OpTrue)
+		} else {
+			c.emit(node.Pos(), code.OpFalse)
+		}
+	case *ast.PrefixExpression:
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+
+		op, ok := prefixOperators[node.Operator]
+		if !ok {
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
+		}
+		c.emit(node.Pos(), op)
+	case *ast.InfixExpression:
+		if err := c.compile(node.Left); err != nil {
+			return err
+		}
+
+		if op, ok := shortCircuitOperators[node.Operator]; ok {
+			jumpPos := c.emit(node.Pos(), op, 9999)
+			if err := c.compile(node.Right); err != nil {
+				return err
+			}
+			c.changeOperand(jumpPos, len(c.currentInstructions()))
+			return nil
+		}
+
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
+		}
+		c.emit(node.Pos(), op)
+	case *ast.ConditionalExpression:
+		return c.compileConditional(node)
+	case *ast.TryExpression:
+		return c.compileTry(node)
+	case *ast.WhileExpression:
+		return c.compileWhile(node)
+	case *ast.ForExpression:
+		return c.compileFor(node)
+	case *ast.Identifier:
+		c.loadName(node.Pos(), node.Value)
+	case *ast.AssignExpression:
+		return c.compileAssign(node)
+	case *ast.FunctionExpression:
+		return c.compileFunction(node)
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node)
+	case *ast.CallExpression:
+		if err := c.compile(node.Function); err != nil {
+			return err
+		}
+		for _, arg := range node.Arguments {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(node.Pos(), code.OpCall, len(node.Arguments))
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(node.Pos(), code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		// compile pairs in source order so the output is deterministic
+		for _, k := range node.OrderedKeys() {
+			if err := c.compile(k); err != nil {
+				return err
+			}
+			if err := c.compile(node.Pairs[k]); err != nil {
+				return err
+			}
+		}
+		c.emit(node.Pos(), code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		if err := c.compile(node.Array); err != nil {
+			return err
+		}
+		if err := c.compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), code.OpIndex)
+	default:
+		return fmt.Errorf("compiler: unsupported node %T", node)
+	}
+
+	return nil
+}
+
+// compileStatements leaves the value of the last statement on the stack,
+// which is the value of a program or a block just like in the evaluator
+func (c *Compiler) compileStatements(statements []ast.Statement) error {
+	for i, s := range statements {
+		if err := c.compile(s); err != nil {
+			return err
+		}
+
+		if i != len(statements)-1 {
+			c.emit(s.Pos(), code.OpPop)
+		}
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileConditional(node *ast.ConditionalExpression) error {
+	// a plain else block has no condition
+	if node.Condition == nil {
+		return c.compile(node.ExecutionBlock)
+	}
+
+	if err := c.compile(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPos := c.emit(node.Pos(), code.OpJumpNotTruthy, 9999)
+
+	if err := c.compile(node.ExecutionBlock); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(node.Pos(), code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if node.NextConditional != nil {
+		if err := c.compileConditional(node.NextConditional); err != nil {
+			return err
+		}
+	} else {
+		c.emit(node.Pos(), code.OpNull)
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+func (c *Compiler) compileAssign(node *ast.AssignExpression) error {
+	if err := c.compile(node.Value); err != nil {
+		return err
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if c.scopeIndex > 0 {
+			c.emit(node.Pos(), code.OpAssignLocal, c.addLocal(target.Value), c.addName(node.Operator))
+		} else {
+			c.emit(node.Pos(), code.OpAssignGlobal, c.addGlobal(target.Value), c.addName(node.Operator))
+		}
+	case *ast.IndexExpression:
+		if err := c.compile(target.Array); err != nil {
+			return err
+		}
+		if err := c.compile(target.Index); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), code.OpSetIndex, c.addName(node.Operator))
+	default:
+		return fmt.Errorf("%s: invalid assignment target", node.Pos())
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileTry(node *ast.TryExpression) error {
+	tryPos := c.emit(node.Pos(), code.OpTry, 9999)
+
+	c.scopes[c.scopeIndex].tries++
+	err := c.compile(node.Body)
+	// compiling a function in the body can move c.scopes
+	c.scopes[c.scopeIndex].tries--
+	if err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), code.OpEndTry)
+	jumpPos := c.emit(node.Pos(), code.OpJump, 9999)
+	c.changeOperand(tryPos, len(c.currentInstructions()))
+
+	// the vm pushes the caught exception before jumping to the handler
+	if node.Variable != nil {
+		c.storeName(node.Pos(), node.Variable.Value)
+	}
+	c.emit(node.Pos(), code.OpPop)
+
+	if err := c.compile(node.Handler); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+func (c *Compiler) compileWhile(node *ast.WhileExpression) error {
+	loopStart := len(c.currentInstructions())
+
+	if err := c.compile(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPos := c.emit(node.Pos(), code.OpJumpNotTruthy, 9999)
+
+	l, err := c.compileLoopBody(node.Body, loopStart)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), code.OpPop)
+	c.emit(node.Pos(), code.OpJump, loopStart)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	c.patchBreaks(l, len(c.currentInstructions()))
+	c.emit(node.Pos(), code.OpNull)
+
+	return nil
+}
+
+func (c *Compiler) compileFor(node *ast.ForExpression) error {
+	bounds := []ast.Expression{node.Iterable}
+	if node.Iterable == nil {
+		bounds = []ast.Expression{node.Start, node.End}
+		if node.Step != nil {
+			bounds = append(bounds, node.Step)
+		}
+	}
+
+	for _, b := range bounds {
+		if err := c.compile(b); err != nil {
+			return err
+		}
+	}
+
+	c.emit(node.Pos(), code.OpIter, len(bounds))
+
+	loopStart := c.emit(node.Pos(), code.OpIterNext, 9999)
+	c.storeName(node.Pos(), node.Variable.Value)
+	c.emit(node.Pos(), code.OpPop)
+
+	l, err := c.compileLoopBody(node.Body, loopStart)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), code.OpPop)
+	c.emit(node.Pos(), code.OpJump, loopStart)
+
+	// break leaves the iterator on the stack, running out of values does not
+	if len(l.breakJumps) > 0 {
+		c.patchBreaks(l, c.emit(node.Pos(), code.OpPop))
+	}
+
+	c.changeOperand(loopStart, len(c.currentInstructions()))
+	c.emit(node.Pos(), code.OpNull)
+
+	return nil
+}
+
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, continueTarget int) (*loop, error) {
+	l := &loop{continueTarget: continueTarget, tries: c.scopes[c.scopeIndex].tries}
+	c.scopes[c.scopeIndex].loops = append(c.scopes[c.scopeIndex].loops, l)
+
+	err := c.compile(body)
+
+	// compiling a function in the body can move c.scopes, so the scope is
+	// looked up again
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	return l, err
+}
+
+// leaveTries removes the handlers of the try blocks a break or continue jumps
+// out of
+func (c *Compiler) leaveTries(pos lexer.Position, l *loop) {
+	for i := l.tries; i < c.scopes[c.scopeIndex].tries; i++ {
+		c.emit(pos, code.OpEndTry)
+	}
+}
+
+func (c *Compiler) patchBreaks(l *loop, target int) {
+	for _, pos := range l.breakJumps {
+		c.changeOperand(pos, target)
+	}
+}
+
+func (c *Compiler) currentLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+func (c *Compiler) compileFunction(node *ast.FunctionExpression) error {
+	fn, err := c.compileFunctionBody(node.TokenLiteral(), node.Parameters, node.Body)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), code.OpClosure, c.addConstant(fn))
+	c.storeName(node.Pos(), node.TokenLiteral())
+	c.emit(node.Pos(), code.OpPop)
+	c.emit(node.Pos(), code.OpNull)
+
+	return nil
+}
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
+	fn, err := c.compileFunctionBody("", node.Parameters, node.Body)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), code.OpClosure, c.addConstant(fn))
+
+	return nil
+}
+
+func (c *Compiler) compileFunctionBody(name string, parameters []*ast.Identifier, body *ast.BlockStatement) (*object.CompiledFunction, error) {
+	c.enterScope()
+
+	params := []string{}
+	for _, p := range parameters {
+		params = append(params, p.Value)
+		c.addParameter(p.Value)
+	}
+	c.declareLocals(body)
+
+	if err := c.compile(body); err != nil {
+		c.leaveScope()
+		return nil, err
+	}
+	// the value of the body is returned when there is no explicit return
+	c.emit(body.Pos(), code.OpReturnValue)
+
+	locals := c.scopes[c.scopeIndex].localNames
+	instructions, positions := c.leaveScope()
+
+	return &object.CompiledFunction{
+		Name:         name,
+		Parameters:   params,
+		Locals:       locals,
+		Instructions: instructions,
+		Positions:    positions,
+	}, nil
+}
+
+// declareLocals gives a slot to every variable body sets. A call sets them
+// in its own scope, so the name refers to the local anywhere in the body and
+// to the variable outside only until the local is set.
+func (c *Compiler) declareLocals(body *ast.BlockStatement) {
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			c.addLocal(node.Name.Value)
+		case *ast.ImportStatement:
+			c.addLocal(node.BindingName())
+		case *ast.AssignExpression:
+			if target, ok := node.Target.(*ast.Identifier); ok {
+				c.addLocal(target.Value)
+			}
+		case *ast.ForExpression:
+			c.addLocal(node.Variable.Value)
+		case *ast.TryExpression:
+			if node.Variable != nil {
+				c.addLocal(node.Variable.Value)
+			}
+		case *ast.FunctionExpression:
+			// the name is set here, the body is a scope of its own
+			c.addLocal(node.TokenLiteral())
+			return false
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return true
+	})
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	// functions keep a reference to the pool so closures stay callable after
+	// the bytecode they came from is gone, e.g. between REPL lines
+	for _, constant := range c.constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			fn.Constants = c.constants
+		}
+	}
+
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+		Globals:      c.globalNames,
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) addName(name string) int {
+	if idx, ok := c.names[name]; ok {
+		return idx
+	}
+
+	idx := c.addConstant(&object.String{Value: name})
+	c.names[name] = idx
+	return idx
+}
+
+func (c *Compiler) addGlobal(name string) int {
+	if idx, ok := c.globals[name]; ok {
+		return idx
+	}
+
+	c.globalNames = append(c.globalNames, name)
+	c.globals[name] = len(c.globalNames) - 1
+	return c.globals[name]
+}
+
+func (c *Compiler) addLocal(name string) int {
+	scope := &c.scopes[c.scopeIndex]
+	if idx, ok := scope.locals[name]; ok {
+		return idx
+	}
+	return c.addParameter(name)
+}
+
+// addParameter always takes a new slot, the last of two parameters with the
+// same name wins like it does in the evaluator
+func (c *Compiler) addParameter(name string) int {
+	scope := &c.scopes[c.scopeIndex]
+	scope.localNames = append(scope.localNames, name)
+	scope.locals[name] = len(scope.localNames) - 1
+	return scope.locals[name]
+}
+
+// loadName reads the innermost variable called name, a global when no
+// function around sets it
+func (c *Compiler) loadName(pos lexer.Position, name string) {
+	for i := c.scopeIndex; i > 0; i-- {
+		slot, ok := c.scopes[i].locals[name]
+		if !ok {
+			continue
+		}
+
+		if i == c.scopeIndex {
+			c.emit(pos, code.OpGetLocal, slot)
+		} else {
+			c.emit(pos, code.OpGetOuter, c.scopeIndex-i, slot)
+		}
+		return
+	}
+
+	c.emit(pos, code.OpGetGlobal, c.addGlobal(name))
+}
+
+// storeName sets the variable called name in the function being compiled,
+// or the global outside of any
+func (c *Compiler) storeName(pos lexer.Position, name string) {
+	if c.scopeIndex > 0 {
+		c.emit(pos, code.OpSetLocal, c.addLocal(name))
+		return
+	}
+	c.emit(pos, code.OpSetGlobal, c.addGlobal(name))
+}
+
+func (c *Compiler) emit(pos lexer.Position, op code.Opcode, operands ...int) int {
+	c.checkOperands(pos, op, operands...)
+
+	ins := code.Make(op, operands...)
+	position := c.addInstruction(ins)
+
+	c.scopes[c.scopeIndex].positions[position] = pos
+
+	return position
+}
+
+// checkOperands remembers the first operand that does not fit, like a jump
+// past the end of a huge program or a call with too many arguments
+func (c *Compiler) checkOperands(pos lexer.Position, op code.Opcode, operands ...int) {
+	if c.err != nil {
+		return
+	}
+
+	if err := code.CheckOperands(op, operands...); err != nil {
+		c.err = fmt.Errorf("%s: program too large: %s", pos, err)
+	}
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	return posNewInstruction
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(c.scopes[c.scopeIndex].positions[opPos], op, operand)
+
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions: code.Instructions{},
+		positions:    make(map[int]lexer.Position),
+		locals:       make(map[string]int),
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+}
+
+func (c *Compiler) leaveScope() (code.Instructions, map[int]lexer.Position) {
+	scope := c.scopes[c.scopeIndex]
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	return scope.instructions, scope.positions
+}
@@ -0,0 +1,392 @@
+package compiler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/compiler"
+	"github.com/SirusCodes/anti-lang/src/object"
+	"github.com/SirusCodes/anti-lang/src/utils"
+)
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+			},
+		},
+		{
+			input:             "1\n2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+			},
+		},
+		{
+			input:             "-1 % 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMod),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "{true} if [ 10 ] else [ 20 ]",
+			expectedConstants: []interface{}{10, 20},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 16),
+				// 0006
+				code.Make(code.OpConstant, 0),
+				// 0011
+				code.Make(code.OpJump, 21),
+				// 0016
+				code.Make(code.OpConstant, 1),
+			},
+		},
+		{
+			input:             "{true} if [ 10 ]",
+			expectedConstants: []interface{}{10},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 16),
+				// 0006
+				code.Make(code.OpConstant, 0),
+				// 0011
+				code.Make(code.OpJump, 17),
+				// 0016
+				code.Make(code.OpNull),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestShortCircuit(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true && 1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpAnd, 11),
+				// 0006
+				code.Make(code.OpConstant, 0),
+			},
+		},
+		{
+			input:             "false || true ?? 2",
+			expectedConstants: []interface{}{2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpFalse),
+				// 0001
+				code.Make(code.OpOr, 7),
+				// 0006
+				code.Make(code.OpTrue),
+				// 0007
+				code.Make(code.OpCoalesce, 17),
+				// 0012
+				code.Make(code.OpConstant, 0),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLetAndAssign(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             ",1 = one let\n,2 += one",
+			expectedConstants: []interface{}{1, 2, "+="},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAssignGlobal, 0, 2),
+			},
+		},
+		{
+			input:             ",5 -= (2)arr",
+			expectedConstants: []interface{}{5, 2, "-="},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetIndex, 2),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestWhileLoop(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "{x} while [ 1 ]",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpGetGlobal, 0),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 21),
+				// 0010
+				code.Make(code.OpConstant, 0),
+				// 0015
+				code.Make(code.OpPop),
+				// 0016
+				code.Make(code.OpJump, 0),
+				// 0021
+				code.Make(code.OpNull),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "try [ 1 ] {e} catch [ e ]",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 16),
+				// 0005
+				code.Make(code.OpConstant, 0),
+				// 0010
+				code.Make(code.OpEndTry),
+				// 0011
+				code.Make(code.OpJump, 27),
+				// 0016
+				code.Make(code.OpSetGlobal, 0),
+				// 0021
+				code.Make(code.OpPop),
+				// 0022
+				code.Make(code.OpGetGlobal, 0),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "{a; b} add func [ ,a + b return ]\n,{1; 2}add",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+					code.Make(code.OpReturnValue),
+				},
+				1,
+				2,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 2),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestVariableScopes(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "{a} f func [ ,{} func [ ,a + b return ] return ]",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetOuter, 1, 0),
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpClosure, 0),
+					code.Make(code.OpReturnValue),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpPop),
+				code.Make(code.OpNull),
+			},
+		},
+		{
+			// x is set in the body, so it is a local even where it is read
+			// before, the vm falls back to the global until it is set
+			input: "{} f func [ ,x + 1 = x ]",
+			expectedConstants: []interface{}{
+				1,
+				"=",
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpAssignLocal, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpNull),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestInstructionPositions(t *testing.T) {
+	program := utils.ParseInput(t, ",1 = a let\n,a + x")
+
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bytecode := c.Bytecode()
+
+	expected := map[int]string{
+		0:  "1:2",
+		5:  "1:8",
+		11: "2:2",
+		16: "2:6",
+		21: "2:4",
+	}
+
+	for offset, pos := range expected {
+		if bytecode.Positions[offset].String() != pos {
+			t.Errorf("wrong position at %04d. want=%s, got=%s", offset, pos, bytecode.Positions[offset])
+		}
+	}
+}
+
+func TestOperandTooLarge(t *testing.T) {
+	args := strings.Repeat("1; ", 70000)
+	program := utils.ParseInput(t, ",{"+args+"1}print")
+
+	err := compiler.New().Compile(program)
+	expected := "program too large: operand 70001 of OpCall does not fit in 2 bytes"
+	if err == nil || !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("expected=%q, got=%v", expected, err)
+	}
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		c := compiler.New()
+		if err := c.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := c.Bytecode()
+
+		testInstructions(t, tt.expectedInstructions, bytecode.Instructions)
+		testConstants(t, tt.expectedConstants, bytecode.Constants)
+	}
+}
+
+func testInstructions(t *testing.T, expected []code.Instructions, actual code.Instructions) {
+	t.Helper()
+
+	concatted := concatInstructions(expected)
+
+	if actual.String() != concatted.String() {
+		t.Errorf("wrong instructions.\nwant=\n%s\ngot=\n%s", concatted, actual)
+	}
+}
+
+func concatInstructions(s []code.Instructions) code.Instructions {
+	out := code.Instructions{}
+	for _, ins := range s {
+		out = append(out, ins...)
+	}
+	return out
+}
+
+func testConstants(t *testing.T, expected []interface{}, actual []object.Object) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(expected), len(actual))
+	}
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*object.Integer)
+			if !ok || integer.Value != int64(constant) {
+				t.Errorf("constant %d - wrong integer. want=%d, got=%+v", i, constant, actual[i])
+			}
+		case string:
+			str, ok := actual[i].(*object.String)
+			if !ok || str.Value != constant {
+				t.Errorf("constant %d - wrong string. want=%q, got=%+v", i, constant, actual[i])
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				t.Errorf("constant %d - not a function: %T", i, actual[i])
+				continue
+			}
+			testInstructions(t, constant, fn.Instructions)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+// evalIntegerArithmetic computes + - * / % ** and // on integers. Division by
+// zero is always an error, overflow only when checked, otherwise the result
+// grows into a BigInt.
+func evalIntegerArithmetic(operator string, left, right int64, checked bool) object.Object {
+	var result int64
+	var overflow bool
+
+	switch operator {
+	case "+":
+		result = left + right
+		overflow = (left >= 0) == (right >= 0) && (result >= 0) != (left >= 0)
+	case "-":
+		result = left - right
+		overflow = (left >= 0) != (right >= 0) && (result >= 0) != (left >= 0)
+	case "*":
+		result, overflow = multiply(left, right)
+	case "/", "//":
+		if right == 0 {
+			return newZeroDivisionError("division by zero")
+		}
+		result = left / right
+		overflow = left == math.MinInt64 && right == -1
+		// / truncates towards zero, // rounds down
+		if operator == "//" && left%right != 0 && (left < 0) != (right < 0) {
+			result--
+		}
+	case "%":
+		if right == 0 {
+			return newZeroDivisionError("division by zero")
+		}
+		result = left % right
+	case "**":
+		if right < 0 {
+			// a negative power is a fraction
+			if left == 0 {
+				return newZeroDivisionError("division by zero")
+			}
+			return &object.Float{Value: math.Pow(float64(left), float64(right))}
+		}
+		result, overflow = power(left, right)
+	}
+
+	if overflow {
+		if checked {
+			return newOverflowError("integer overflow: %d %s %d", left, operator, right)
+		}
+		return evalBigIntInfixExpression(operator, big.NewInt(left), big.NewInt(right))
+	}
+
+	return &object.Integer{Value: result}
+}
+
+func evalIntegerNegation(value int64, checked bool) object.Object {
+	if value == math.MinInt64 {
+		if checked {
+			return newOverflowError("integer overflow: -(%d)", value)
+		}
+		return &object.BigInt{Value: new(big.Int).Neg(big.NewInt(value))}
+	}
+	return &object.Integer{Value: -value}
+}
+
+// multiply returns the wrapped product of a and b and whether it overflowed
+func multiply(a, b int64) (int64, bool) {
+	result := a * b
+	return result, a != 0 && (result/a != b || (a == -1 && b == math.MinInt64))
+}
+
+// power raises base to a non-negative exponent by squaring
+func power(base, exponent int64) (int64, bool) {
+	result, overflow := int64(1), false
+
+	for exponent > 0 {
+		var o bool
+		if exponent&1 == 1 {
+			result, o = multiply(result, base)
+			overflow = overflow || o
+		}
+		exponent >>= 1
+		if exponent > 0 {
+			base, o = multiply(base, base)
+			overflow = overflow || o
+		}
+	}
+
+	return result, overflow
+}
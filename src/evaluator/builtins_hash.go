@@ -0,0 +1,150 @@
+package evaluator
+
+import "github.com/SirusCodes/anti-lang/src/object"
+
+// The hash builtins never change the hash they are given, like push and pop
+// they return a new one.
+
+func hashArg(name string, args []object.Object, want int) (*object.Hash, *object.Error) {
+	if len(args) != want {
+		return nil, newArgumentError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newTypeError("first argument to `%s` must be HASH, got %s", name, args[0].Type())
+	}
+
+	return hash, nil
+}
+
+func hashKeyOf(key object.Object) (object.HashKey, *object.Error) {
+	hashable, ok := key.(object.Hashable)
+	if !ok {
+		return object.HashKey{}, newKeyError("unusable as hash key: %s", key.Type())
+	}
+	return hashable.HashKey(), nil
+}
+
+func builtinKeys(ctx *object.CallContext, args ...object.Object) object.Object {
+	hash, err := hashArg("keys", args, 1)
+	if err != nil {
+		return err
+	}
+
+	keys := []object.Object{}
+	for _, pair := range hash.Ordered() {
+		keys = append(keys, pair.Key)
+	}
+
+	return &object.Array{Elements: keys}
+}
+
+func builtinValues(ctx *object.CallContext, args ...object.Object) object.Object {
+	hash, err := hashArg("values", args, 1)
+	if err != nil {
+		return err
+	}
+
+	values := []object.Object{}
+	for _, pair := range hash.Ordered() {
+		values = append(values, pair.Value)
+	}
+
+	return &object.Array{Elements: values}
+}
+
+// entries returns the pairs as (key; value) arrays
+func builtinEntries(ctx *object.CallContext, args ...object.Object) object.Object {
+	hash, err := hashArg("entries", args, 1)
+	if err != nil {
+		return err
+	}
+
+	entries := []object.Object{}
+	for _, pair := range hash.Ordered() {
+		entries = append(entries, &object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+	}
+
+	return &object.Array{Elements: entries}
+}
+
+func builtinHas(ctx *object.CallContext, args ...object.Object) object.Object {
+	hash, err := hashArg("has", args, 2)
+	if err != nil {
+		return err
+	}
+
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	_, ok := hash.Get(key)
+	return nativeBoolToBooleanObject(ok)
+}
+
+func builtinSet(ctx *object.CallContext, args ...object.Object) object.Object {
+	hash, err := hashArg("set", args, 3)
+	if err != nil {
+		return err
+	}
+
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	newHash := hash.Copy()
+	newHash.Set(key, object.HashPair{Key: args[1], Value: args[2]})
+
+	return newHash
+}
+
+func builtinDelete(ctx *object.CallContext, args ...object.Object) object.Object {
+	hash, err := hashArg("delete", args, 2)
+	if err != nil {
+		return err
+	}
+
+	key, err := hashKeyOf(args[1])
+	if err != nil {
+		return err
+	}
+
+	newHash := hash.Copy()
+	newHash.Delete(key)
+
+	return newHash
+}
+
+// merge combines the hashes from left to right, later values win
+func builtinMerge(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) < 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want at least 2", len(args))
+	}
+
+	merged := object.NewHash()
+	for _, arg := range args {
+		hash, ok := arg.(*object.Hash)
+		if !ok {
+			return newTypeError("arguments to `merge` must be HASH, got %s", arg.Type())
+		}
+
+		for _, key := range hash.Keys {
+			merged.Set(key, hash.Pairs[key])
+		}
+	}
+
+	return merged
+}
+
+func init() {
+	registerBuiltIns("keys", builtinKeys)
+	registerBuiltIns("values", builtinValues)
+	registerBuiltIns("entries", builtinEntries)
+	registerBuiltIns("has", builtinHas)
+	registerBuiltIns("set", builtinSet)
+	registerBuiltIns("delete", builtinDelete)
+	registerBuiltIns("merge", builtinMerge)
+}
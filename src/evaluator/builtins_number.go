@@ -0,0 +1,59 @@
+package evaluator
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+// bigint converts integers and strings of digits to a big integer
+func builtinBigInt(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer, *object.BigInt:
+		return &object.BigInt{Value: new(big.Int).Set(toBigInt(arg))}
+	case *object.String:
+		value, ok := new(big.Int).SetString(arg.Value, 10)
+		if !ok {
+			return newValueError("could not parse %s as bigint", arg.Value)
+		}
+		return &object.BigInt{Value: value}
+	default:
+		return newTypeError("argument to `bigint` not supported, got %s", arg.Type())
+	}
+}
+
+// decimal converts numbers and strings to a decimal, a float becomes the
+// shortest decimal that reads back as the same float
+func builtinDecimal(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	var text string
+	switch arg := args[0].(type) {
+	case *object.Integer, *object.BigInt, *object.Decimal:
+		return toDecimal(arg)
+	case *object.Float:
+		text = strconv.FormatFloat(arg.Value, 'f', -1, 64)
+	case *object.String:
+		text = arg.Value
+	default:
+		return newTypeError("argument to `decimal` not supported, got %s", arg.Type())
+	}
+
+	decimal, err := object.ParseDecimal(text)
+	if err != nil {
+		return newValueError("%s", err)
+	}
+	return decimal
+}
+
+func init() {
+	registerBuiltIns("bigint", builtinBigInt)
+	registerBuiltIns("decimal", builtinDecimal)
+}
@@ -0,0 +1,252 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+var ordinals = []string{"first", "second", "third"}
+
+// maxRepeatLength is the longest string in bytes repeat builds, anything
+// longer would only run out of memory
+const maxRepeatLength = 1 << 30
+
+// stringArgs checks that every argument is a STRING and returns their values
+func stringArgs(name string, args []object.Object, want int) ([]string, *object.Error) {
+	if len(args) != want {
+		return nil, newArgumentError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	values := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, newTypeError("%s argument to `%s` must be STRING, got %s", ordinals[i], name, arg.Type())
+		}
+		values[i] = str.Value
+	}
+
+	return values, nil
+}
+
+func stringsToArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, value := range values {
+		elements[i] = &object.String{Value: value}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func builtinSplit(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("split", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return stringsToArray(strings.Split(values[0], values[1]))
+}
+
+func builtinJoin(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newTypeError("first argument to `join` must be ARRAY, got %s", args[0].Type())
+	}
+
+	sep := ""
+	if len(args) == 2 {
+		str, ok := args[1].(*object.String)
+		if !ok {
+			return newTypeError("second argument to `join` must be STRING, got %s", args[1].Type())
+		}
+		sep = str.Value
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, el := range arr.Elements {
+		parts[i] = el.Inspect()
+	}
+
+	return &object.String{Value: strings.Join(parts, sep)}
+}
+
+func builtinTrim(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("trim", args, 1)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.TrimSpace(values[0])}
+}
+
+func builtinUpper(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("upper", args, 1)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToUpper(values[0])}
+}
+
+func builtinLower(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("lower", args, 1)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToLower(values[0])}
+}
+
+// replace swaps every occurrence of old with new
+func builtinReplace(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("replace", args, 3)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
+}
+
+func builtinContains(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("contains", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(strings.Contains(values[0], values[1]))
+}
+
+func builtinStartsWith(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("startsWith", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(strings.HasPrefix(values[0], values[1]))
+}
+
+func builtinEndsWith(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("endsWith", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(strings.HasSuffix(values[0], values[1]))
+}
+
+// indexOf returns the 1-based position of the first occurrence, 0 when the
+// string does not contain it
+func builtinIndexOf(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("indexOf", args, 2)
+	if err != nil {
+		return err
+	}
+
+	idx := strings.Index(values[0], values[1])
+	if idx == -1 {
+		return &object.Integer{Value: 0}
+	}
+
+	return &object.Integer{Value: int64(len([]rune(values[0][:idx]))) + 1}
+}
+
+// substr returns length characters starting at the 1-based start, or the
+// rest of the string when length is left out
+func builtinSubstr(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newTypeError("first argument to `substr` must be STRING, got %s", args[0].Type())
+	}
+
+	bounds := make([]int64, len(args)-1)
+	for i, arg := range args[1:] {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newTypeError("%s argument to `substr` must be INTEGER, got %s", ordinals[i+1], arg.Type())
+		}
+		bounds[i] = integer.Value
+	}
+
+	chars := []rune(str.Value)
+	start := bounds[0]
+	if start < 1 || start > int64(len(chars))+1 {
+		return newIndexError("index out of bounds")
+	}
+
+	end := int64(len(chars))
+	if len(bounds) == 2 {
+		if bounds[1] < 0 {
+			return newValueError("length must not be negative")
+		}
+		// compared before adding, a huge length would overflow
+		if bounds[1] < end-(start-1) {
+			end = start - 1 + bounds[1]
+		}
+	}
+
+	return &object.String{Value: string(chars[start-1 : end])}
+}
+
+func builtinRepeat(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newTypeError("first argument to `repeat` must be STRING, got %s", args[0].Type())
+	}
+
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return newTypeError("second argument to `repeat` must be INTEGER, got %s", args[1].Type())
+	}
+
+	if count.Value < 0 {
+		return newValueError("count must not be negative")
+	}
+
+	if len(str.Value) > 0 && count.Value > maxRepeatLength/int64(len(str.Value)) {
+		return newValueError("repeated string too long: %d times %d bytes", count.Value, len(str.Value))
+	}
+
+	return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+}
+
+func builtinChars(ctx *object.CallContext, args ...object.Object) object.Object {
+	values, err := stringArgs("chars", args, 1)
+	if err != nil {
+		return err
+	}
+
+	chars := []string{}
+	for _, ch := range values[0] {
+		chars = append(chars, string(ch))
+	}
+
+	return stringsToArray(chars)
+}
+
+func init() {
+	registerBuiltIns("split", builtinSplit)
+	registerBuiltIns("join", builtinJoin)
+	registerBuiltIns("trim", builtinTrim)
+	registerBuiltIns("upper", builtinUpper)
+	registerBuiltIns("lower", builtinLower)
+	registerBuiltIns("replace", builtinReplace)
+	registerBuiltIns("contains", builtinContains)
+	registerBuiltIns("startsWith", builtinStartsWith)
+	registerBuiltIns("endsWith", builtinEndsWith)
+	registerBuiltIns("indexOf", builtinIndexOf)
+	registerBuiltIns("substr", builtinSubstr)
+	registerBuiltIns("repeat", builtinRepeat)
+	registerBuiltIns("chars", builtinChars)
+}
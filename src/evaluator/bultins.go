@@ -2,39 +2,70 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/SirusCodes/anti-lang/src/object"
 )
 
-var builtins = make(map[string]*object.Builtin)
+var (
+	builtins   = make(map[string]*object.Builtin)
+	builtinsMu sync.RWMutex // RegisterBuiltin may run next to programs
+)
 
 func registerBuiltIns(name string, fn object.BuiltinFunction) {
 	builtins[name] = &object.Builtin{Fn: fn}
 }
 
+// RegisterBuiltin makes fn callable as name from every program, replacing
+// the builtin of that name if there is one. Variables of the program with
+// the same name still hide it.
+func RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+
+	builtins[name] = &object.Builtin{Fn: fn}
+}
+
+// lookupBuiltin finds name among the builtins of the program env belongs to
+// and then among the ones every program has
+func lookupBuiltin(name string, env *object.Environment) (*object.Builtin, bool) {
+	if builtin, ok := env.Builtins()[name]; ok {
+		return builtin, true
+	}
+
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
 // Built-in function to get the length
-func builtinLen(args ...object.Object) object.Object {
+func builtinLen(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	switch arg := args[0].(type) {
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.Value))}
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.Elements))}
 	default:
-		return newError("argument to `len` not supported, got %s", args[0].Type())
+		return newTypeError("argument to `len` not supported, got %s", args[0].Type())
 	}
 }
 
-func builtinFirst(args ...object.Object) object.Object {
+func builtinFirst(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("argument to `first` must be ARRAY, got %s", args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -45,13 +76,13 @@ func builtinFirst(args ...object.Object) object.Object {
 	return NULL
 }
 
-func builtinLast(args ...object.Object) object.Object {
+func builtinLast(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("argument to `last` must be ARRAY, got %s", args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -63,13 +94,13 @@ func builtinLast(args ...object.Object) object.Object {
 	return NULL
 }
 
-func builtinRest(args ...object.Object) object.Object {
+func builtinRest(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("argument to `rest` must be ARRAY, got %s", args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -83,13 +114,13 @@ func builtinRest(args ...object.Object) object.Object {
 	return NULL
 }
 
-func builtinPush(args ...object.Object) object.Object {
+func builtinPush(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 2 {
-		return newError("wrong number of arguments. got=%d, want=2", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("argument to `push` must be ARRAY, got %s", args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -102,13 +133,13 @@ func builtinPush(args ...object.Object) object.Object {
 	return &object.Array{Elements: newElements}
 }
 
-func builtinPop(args ...object.Object) object.Object {
+func builtinPop(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `pop` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("argument to `pop` must be ARRAY, got %s", args[0].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -123,17 +154,17 @@ func builtinPop(args ...object.Object) object.Object {
 	return NULL
 }
 
-func builtinAddAt(args ...object.Object) object.Object {
+func builtinAddAt(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 3 {
-		return newError("wrong number of arguments. got=%d, want=3", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=3", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("first argument to `addAt` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("first argument to `addAt` must be ARRAY, got %s", args[0].Type())
 	}
 
 	if args[1].Type() != object.INTEGER_OBJ {
-		return newError("second argument to `addAt` must be INTEGER, got %s", args[1].Type())
+		return newTypeError("second argument to `addAt` must be INTEGER, got %s", args[1].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -142,7 +173,7 @@ func builtinAddAt(args ...object.Object) object.Object {
 
 	length := len(arr.Elements)
 	if index < 0 || index > int64(length) {
-		return newError("index out of bounds")
+		return newIndexError("index out of bounds")
 	}
 
 	newElements := make([]object.Object, length+1)
@@ -153,17 +184,17 @@ func builtinAddAt(args ...object.Object) object.Object {
 	return &object.Array{Elements: newElements}
 }
 
-func builtinRemoveAt(args ...object.Object) object.Object {
+func builtinRemoveAt(ctx *object.CallContext, args ...object.Object) object.Object {
 	if len(args) != 2 {
-		return newError("wrong number of arguments. got=%d, want=2", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("first argument to `removeAt` must be ARRAY, got %s", args[0].Type())
+		return newTypeError("first argument to `removeAt` must be ARRAY, got %s", args[0].Type())
 	}
 
 	if args[1].Type() != object.INTEGER_OBJ {
-		return newError("second argument to `removeAt` must be INTEGER, got %s", args[1].Type())
+		return newTypeError("second argument to `removeAt` must be INTEGER, got %s", args[1].Type())
 	}
 
 	arr := args[0].(*object.Array)
@@ -171,7 +202,7 @@ func builtinRemoveAt(args ...object.Object) object.Object {
 
 	length := len(arr.Elements)
 	if index < 0 || index >= int64(length) {
-		return newError("index out of bounds")
+		return newIndexError("index out of bounds")
 	}
 
 	newElements := make([]object.Object, length-1)
@@ -181,13 +212,315 @@ func builtinRemoveAt(args ...object.Object) object.Object {
 	return &object.Array{Elements: newElements}
 }
 
-func builtinPrint(args ...object.Object) object.Object {
+func builtinPrint(ctx *object.CallContext, args ...object.Object) object.Object {
+	for _, arg := range args {
+		fmt.Fprintln(streams(ctx).Stdout, arg.Inspect())
+	}
+	return NULL
+}
+
+// eprint is print for standard error
+func builtinEprint(ctx *object.CallContext, args ...object.Object) object.Object {
 	for _, arg := range args {
-		fmt.Println(arg.Inspect())
+		fmt.Fprintln(streams(ctx).Stderr, arg.Inspect())
+	}
+	return NULL
+}
+
+// input prints an optional prompt and reads a line, without the line break,
+// from standard input. It returns null once the input has run out.
+func builtinInput(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	if len(args) == 1 {
+		prompt, ok := args[0].(*object.String)
+		if !ok {
+			return newTypeError("argument to `input` must be STRING, got %s", args[0].Type())
+		}
+		fmt.Fprint(streams(ctx).Stdout, prompt.Value)
+	}
+
+	line, err := streams(ctx).Stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return NULL
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	return &object.String{Value: strings.TrimSuffix(line, "\r")}
+}
+
+// streams returns the streams of the program calling a builtin
+func streams(ctx *object.CallContext) *object.Streams {
+	if ctx == nil || ctx.Streams == nil {
+		return object.DefaultStreams
+	}
+	return ctx.Streams
+}
+
+// isCallable reports whether obj can be passed to CallContext.Call
+func isCallable(obj object.Object) bool {
+	return obj.Type() == object.FUNCTION_OBJ || obj.Type() == object.BUILTIN_OBJ
+}
+
+// checkArrayAndCallback validates the `{array; fn}name` arguments shared by
+// the higher-order builtins
+func checkArrayAndCallback(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newTypeError("first argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+
+	if !isCallable(args[1]) {
+		return nil, nil, newTypeError("second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+
+	return arr, args[1], nil
+}
+
+func builtinMap(ctx *object.CallContext, args ...object.Object) object.Object {
+	arr, fn, err := checkArrayAndCallback("map", args)
+	if err != nil {
+		return err
+	}
+
+	newElements := make([]object.Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		result := ctx.Call(fn, el)
+		if isError(result) {
+			return result
+		}
+		newElements[i] = result
+	}
+
+	return &object.Array{Elements: newElements}
+}
+
+func builtinFilter(ctx *object.CallContext, args ...object.Object) object.Object {
+	arr, fn, err := checkArrayAndCallback("filter", args)
+	if err != nil {
+		return err
+	}
+
+	newElements := []object.Object{}
+	for _, el := range arr.Elements {
+		result := ctx.Call(fn, el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			newElements = append(newElements, el)
+		}
+	}
+
+	return &object.Array{Elements: newElements}
+}
+
+func builtinReduce(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	arr, fn, err := checkArrayAndCallback("reduce", args[:2])
+	if err != nil {
+		return err
 	}
+
+	elements := arr.Elements
+	var acc object.Object
+
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(elements) == 0 {
+			return newError("`reduce` of empty array with no initial value")
+		}
+		acc, elements = elements[0], elements[1:]
+	}
+
+	for _, el := range elements {
+		acc = ctx.Call(fn, acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+
+	return acc
+}
+
+func builtinSort(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newTypeError("first argument to `sort` must be ARRAY, got %s", args[0].Type())
+	}
+
+	// without a comparator elements are ordered by `<`
+	less := func(a, b object.Object) object.Object {
+		return evalInfixExpression("<", a, b, false)
+	}
+
+	if len(args) == 2 {
+		if !isCallable(args[1]) {
+			return newTypeError("second argument to `sort` must be FUNCTION, got %s", args[1].Type())
+		}
+		less = func(a, b object.Object) object.Object {
+			return ctx.Call(args[1], a, b)
+		}
+	}
+
+	newElements := make([]object.Object, len(arr.Elements))
+	copy(newElements, arr.Elements)
+
+	var failed object.Object
+	sort.SliceStable(newElements, func(i, j int) bool {
+		if failed != nil {
+			return false
+		}
+
+		result := less(newElements[i], newElements[j])
+		if isError(result) {
+			failed = result
+			return false
+		}
+		return isTruthy(result)
+	})
+
+	if failed != nil {
+		return failed
+	}
+
+	return &object.Array{Elements: newElements}
+}
+
+func builtinFind(ctx *object.CallContext, args ...object.Object) object.Object {
+	arr, fn, err := checkArrayAndCallback("find", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := ctx.Call(fn, el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return el
+		}
+	}
+
 	return NULL
 }
 
+func builtinAny(ctx *object.CallContext, args ...object.Object) object.Object {
+	arr, fn, err := checkArrayAndCallback("any", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := ctx.Call(fn, el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return TRUE
+		}
+	}
+
+	return FALSE
+}
+
+func builtinAll(ctx *object.CallContext, args ...object.Object) object.Object {
+	arr, fn, err := checkArrayAndCallback("all", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := ctx.Call(fn, el)
+		if isError(result) {
+			return result
+		}
+		if !isTruthy(result) {
+			return FALSE
+		}
+	}
+
+	return TRUE
+}
+
+// zip pairs up the elements of the arrays, stopping at the shortest one
+func builtinZip(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) < 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want at least 2", len(args))
+	}
+
+	arrays := make([]*object.Array, len(args))
+	length := -1
+	for i, arg := range args {
+		arr, ok := arg.(*object.Array)
+		if !ok {
+			return newTypeError("arguments to `zip` must be ARRAY, got %s", arg.Type())
+		}
+		arrays[i] = arr
+
+		if length == -1 || len(arr.Elements) < length {
+			length = len(arr.Elements)
+		}
+	}
+
+	tuples := make([]object.Object, length)
+	for i := 0; i < length; i++ {
+		tuple := make([]object.Object, len(arrays))
+		for j, arr := range arrays {
+			tuple[j] = arr.Elements[i]
+		}
+		tuples[i] = &object.Array{Elements: tuple}
+	}
+
+	return &object.Array{Elements: tuples}
+}
+
+// range builds the array of an inclusive range, `{n}range` counts from 1 to n
+func builtinRange(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+	}
+
+	if len(args) == 1 {
+		end, ok := args[0].(*object.Integer)
+		if !ok {
+			return newTypeError("range bounds must be INTEGER, got %s", args[0].Type())
+		}
+		if end.Value < 1 {
+			return &object.Array{Elements: []object.Object{}}
+		}
+		args = []object.Object{&object.Integer{Value: 1}, end}
+	}
+
+	iterator := newRangeIterator(args)
+	if isError(iterator) {
+		return iterator
+	}
+
+	elements := []object.Object{}
+	next := iterator.(*object.Iterator).Next
+	for value, ok := next(); ok; value, ok = next() {
+		elements = append(elements, value)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
 // Registering built-in functions
 func init() {
 	registerBuiltIns("len", builtinLen)
@@ -199,4 +532,15 @@ func init() {
 	registerBuiltIns("addAt", builtinAddAt)
 	registerBuiltIns("removeAt", builtinRemoveAt)
 	registerBuiltIns("print", builtinPrint)
+	registerBuiltIns("eprint", builtinEprint)
+	registerBuiltIns("input", builtinInput)
+	registerBuiltIns("map", builtinMap)
+	registerBuiltIns("filter", builtinFilter)
+	registerBuiltIns("reduce", builtinReduce)
+	registerBuiltIns("sort", builtinSort)
+	registerBuiltIns("find", builtinFind)
+	registerBuiltIns("any", builtinAny)
+	registerBuiltIns("all", builtinAll)
+	registerBuiltIns("zip", builtinZip)
+	registerBuiltIns("range", builtinRange)
 }
@@ -0,0 +1,92 @@
+package evaluator
+
+import "github.com/SirusCodes/anti-lang/src/object"
+
+// Kinds of the errors raised by the interpreter, catch handlers tell them
+// apart by the kind of the exception
+const (
+	TypeError          = "TypeError"
+	ValueError         = "ValueError"
+	NameError          = "NameError"
+	IndexError         = "IndexError"
+	KeyError           = "KeyError"
+	ZeroDivisionError  = "ZeroDivisionError"
+	OverflowError      = "OverflowError"
+	ArgumentError      = "ArgumentError"
+	StackOverflowError = "StackOverflowError"
+)
+
+func newKindError(kind, format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Kind = kind
+	return err
+}
+
+func newTypeError(format string, a ...interface{}) *object.Error {
+	return newKindError(TypeError, format, a...)
+}
+
+func newValueError(format string, a ...interface{}) *object.Error {
+	return newKindError(ValueError, format, a...)
+}
+
+func newNameError(format string, a ...interface{}) *object.Error {
+	return newKindError(NameError, format, a...)
+}
+
+func newIndexError(format string, a ...interface{}) *object.Error {
+	return newKindError(IndexError, format, a...)
+}
+
+func newKeyError(format string, a ...interface{}) *object.Error {
+	return newKindError(KeyError, format, a...)
+}
+
+func newZeroDivisionError(format string, a ...interface{}) *object.Error {
+	return newKindError(ZeroDivisionError, format, a...)
+}
+
+func newOverflowError(format string, a ...interface{}) *object.Error {
+	return newKindError(OverflowError, format, a...)
+}
+
+func newArgumentError(format string, a ...interface{}) *object.Error {
+	return newKindError(ArgumentError, format, a...)
+}
+
+func newStackOverflowError(format string, a ...interface{}) *object.Error {
+	return newKindError(StackOverflowError, format, a...)
+}
+
+// raise fails with a new error, or raises a caught exception again
+func builtinRaise(ctx *object.CallContext, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	if exception, ok := args[0].(*object.Exception); ok && len(args) == 1 {
+		err := *exception.Err
+		err.Stack = append([]object.Frame{}, err.Stack...)
+		return &err
+	}
+
+	message, ok := args[0].(*object.String)
+	if !ok {
+		return newTypeError("first argument to `raise` must be STRING or EXCEPTION, got %s", args[0].Type())
+	}
+
+	err := &object.Error{Message: message.Value}
+	if len(args) == 2 {
+		kind, ok := args[1].(*object.String)
+		if !ok {
+			return newTypeError("second argument to `raise` must be STRING, got %s", args[1].Type())
+		}
+		err.Kind = kind.Value
+	}
+
+	return err
+}
+
+func init() {
+	registerBuiltIns("raise", builtinRaise)
+}
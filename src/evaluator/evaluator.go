@@ -2,18 +2,37 @@ package evaluator
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/SirusCodes/anti-lang/src/ast"
 	"github.com/SirusCodes/anti-lang/src/object"
 )
 
+// MaxCallDepth is how deep function calls can nest before the program fails
+// with a stack overflow, the same depth the vm allows
+const MaxCallDepth = 1023
+
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// attach the position of the innermost node that produced the error
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgam(node, env)
@@ -23,6 +42,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Integer{Value: node.Value}
 	case *ast.FloatLiteral:
 		return &object.Float{Value: node.Value}
+	case *ast.BigIntLiteral:
+		return &object.BigInt{Value: node.Value}
+	case *ast.DecimalLiteral:
+		decimal, err := object.ParseDecimal(node.Value)
+		if err != nil {
+			return newValueError("%s", err)
+		}
+		return decimal
 	case *ast.BooleanLiteral:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
@@ -30,41 +57,63 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(node.Operator, right, env.CheckedArithmetic())
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if shortCircuitOperators[node.Operator] {
+			if shortCircuits(node.Operator, left) {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env.CheckedArithmetic())
 	case *ast.BlockStatement:
 		return evalBlockStatements(node.Statements, env)
 	case *ast.ConditionalExpression:
 		return evalIfExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		return env.Set(node.Name.Value, val)
+	case *ast.ImportStatement:
+		return evalImport(node.Path.Value, node.BindingName(), env)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
+	case *ast.MemberExpression:
+		obj := Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
 	case *ast.FunctionExpression:
 		params := node.Parameters
 		body := node.Body
 
-		env.Set(node.TokenLiteral(), &object.Function{Parameters: params, Body: body, Env: env})
+		env.Set(node.TokenLiteral(), &object.Function{Name: node.TokenLiteral(), Parameters: params, Body: body, Env: env})
 		return NULL
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
 		if isError(function) {
@@ -75,7 +124,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		result := applyFunction(function, args, env)
+		if err, ok := result.(*object.Error); ok {
+			if fn, ok := function.(*object.Function); ok {
+				err.Stack = append(err.Stack, object.Frame{Function: fn.Name, Pos: node.Pos()})
+			}
+		}
+
+		return result
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.ArrayLiteral:
@@ -102,7 +158,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		return evalAssignExpression(node.Name.TokenLiteral(), node.Operator, val, env)
+		return evalAssignTarget(node, val, env)
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
 	case *ast.WhileExpression:
 		for {
 			condition := Eval(node.Condition, env)
@@ -114,15 +172,155 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 				break
 			}
 
-			rt := Eval(node.Body, env)
-			if rt.Type() == object.RETURN_VALUE_OBJ || rt.Type() == object.ERROR_OBJ {
+			if rt, done := evalLoopBody(node.Body, env); done {
 				return rt
 			}
 		}
+		return NULL
 	}
 	return nil
 }
 
+// evalLoopBody runs a single iteration of a loop, done reports whether the
+// loop has to stop and return rt
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (rt object.Object, done bool) {
+	result := Eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	case object.BREAK_OBJ:
+		return NULL, true
+	}
+
+	return nil, false
+}
+
+func evalForExpression(node *ast.ForExpression, env *object.Environment) object.Object {
+	var bounds []ast.Expression
+	if node.Iterable != nil {
+		bounds = []ast.Expression{node.Iterable}
+	} else {
+		bounds = []ast.Expression{node.Start, node.End}
+		if node.Step != nil {
+			bounds = append(bounds, node.Step)
+		}
+	}
+
+	args := evalExpressions(bounds, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	iterator := newIterator(args)
+	if isError(iterator) {
+		return iterator
+	}
+
+	next := iterator.(*object.Iterator).Next
+	for {
+		value, ok := next()
+		if !ok {
+			break
+		}
+
+		env.Set(node.Variable.Value, value)
+
+		if rt, done := evalLoopBody(node.Body, env); done {
+			return rt
+		}
+	}
+
+	return NULL
+}
+
+// newIterator creates the iterator a for loop walks, args is either a single
+// iterable or the start, end and optional step of an inclusive range
+func newIterator(args []object.Object) object.Object {
+	if len(args) > 1 {
+		return newRangeIterator(args)
+	}
+
+	switch iterable := args[0].(type) {
+	case *object.Array:
+		return sliceIterator(iterable.Elements)
+	case *object.Hash:
+		keys := []object.Object{}
+		for _, pair := range iterable.Ordered() {
+			keys = append(keys, pair.Key)
+		}
+		return sliceIterator(keys)
+	case *object.String:
+		chars := []object.Object{}
+		for _, ch := range iterable.Value {
+			chars = append(chars, &object.String{Value: string(ch)})
+		}
+		return sliceIterator(chars)
+	default:
+		return newTypeError("for expects ARRAY, HASH or STRING, got %s", args[0].Type())
+	}
+}
+
+func sliceIterator(values []object.Object) *object.Iterator {
+	idx := 0
+	return &object.Iterator{Next: func() (object.Object, bool) {
+		if idx >= len(values) {
+			return nil, false
+		}
+		idx++
+		return values[idx-1], true
+	}}
+}
+
+func newRangeIterator(args []object.Object) object.Object {
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newTypeError("range bounds must be INTEGER, got %s", arg.Type())
+		}
+		bounds[i] = integer.Value
+	}
+
+	current, end := bounds[0], bounds[1]
+
+	step := int64(1)
+	if len(bounds) == 3 {
+		step = bounds[2]
+	} else if current > end {
+		step = -1
+	}
+
+	if step == 0 {
+		return newValueError("range step must not be 0")
+	}
+
+	done := (step > 0 && current > end) || (step < 0 && current < end)
+
+	return &object.Iterator{Next: func() (object.Object, bool) {
+		if done {
+			return nil, false
+		}
+		value := current
+
+		// the distance left to end is unsigned so it can't overflow, and
+		// current only moves when the step does not pass end
+		if step > 0 {
+			done = uint64(end)-uint64(current) < uint64(step)
+		} else {
+			done = uint64(current)-uint64(end) < -uint64(step)
+		}
+		if !done {
+			current += step
+		}
+
+		return &object.Integer{Value: value}, true
+	}}
+}
+
 func evalProgam(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -133,12 +331,18 @@ func evalProgam(program *ast.Program, env *object.Environment) object.Object {
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Break, *object.Continue:
+			return loopControlError(result)
 		}
 	}
 
 	return result
 }
 
+func loopControlError(signal object.Object) *object.Error {
+	return newError("%s outside of loop", signal.Inspect())
+}
+
 func evalBlockStatements(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -146,7 +350,7 @@ func evalBlockStatements(statements []ast.Statement, env *object.Environment) ob
 		result = Eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -162,14 +366,16 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+// evalPrefixExpression applies operator to right, checked makes integer
+// overflow an error
+func evalPrefixExpression(operator string, right object.Object, checked bool) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, checked)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newTypeError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -186,107 +392,86 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+func evalMinusPrefixOperatorExpression(right object.Object, checked bool) object.Object {
 	switch right.Type() {
 	case object.INTEGER_OBJ:
-		value := right.(*object.Integer).Value
-		return &object.Integer{Value: -value}
+		return evalIntegerNegation(right.(*object.Integer).Value, checked)
 	case object.FLOAT_OBJ:
 		value := right.(*object.Float).Value
 		return &object.Float{Value: -value}
+	case object.BIGINT_OBJ:
+		return &object.BigInt{Value: new(big.Int).Neg(right.(*object.BigInt).Value)}
+	case object.DECIMAL_OBJ:
+		return right.(*object.Decimal).Neg()
 	default:
-		return newError("unknown operator: -%s", right.Type())
+		return newTypeError("unknown operator: -%s", right.Type())
 	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+// evalInfixExpression applies operator to left and right, checked makes
+// integer overflow an error
+func evalInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
+	rank := promotedRank(left, right)
+
 	switch {
+	case rank > 0:
+		return evalNumericInfixExpression(operator, rank, left, right, checked)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
-	case left.Type() == object.FLOAT_OBJ && right.Type() == object.FLOAT_OBJ:
-		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
 		return evalBooleanInfixExpression(operator, left, right)
-	case left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalStringInfixExpression(operator, left, convertIntegerObjectToString(right))
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, convertIntegerObjectToString(left), right)
-	case left.Type() == object.STRING_OBJ && right.Type() == object.FLOAT_OBJ:
+	// numbers are only turned into strings for concatenation, comparing them
+	// with strings is a type mismatch
+	case operator == "+" && left.Type() == object.STRING_OBJ && right.Type() == object.FLOAT_OBJ:
 		return evalStringInfixExpression(operator, left, convertFloatObjectToString(right))
-	case left.Type() == object.FLOAT_OBJ && right.Type() == object.STRING_OBJ:
+	case operator == "+" && left.Type() == object.FLOAT_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, convertFloatObjectToString(left), right)
-	case left.Type() == object.FLOAT_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalFloatInfixExpression(operator, convertFloatToInteger(left), right)
-	case left.Type() == object.INTEGER_OBJ && right.Type() == object.FLOAT_OBJ:
-		return evalFloatInfixExpression(operator, left, convertFloatToInteger(right))
+	case operator == "+" && left.Type() == object.STRING_OBJ && numericRank(right) > 0:
+		return evalStringInfixExpression(operator, left, &object.String{Value: right.Inspect()})
+	case operator == "+" && numericRank(left) > 0 && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, &object.String{Value: left.Inspect()}, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
-}
-
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
-	case "%":
-		return &object.Integer{Value: leftVal % rightVal}
+		return &object.String{Value: leftVal + rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
 	case "<=":
 		return nativeBoolToBooleanObject(leftVal <= rightVal)
 	case ">=":
 		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Float).Value
-	rightVal := right.(*object.Float).Value
+func evalIntegerInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
+	leftVal := left.(*object.Integer).Value
+	rightVal := right.(*object.Integer).Value
 
 	switch operator {
-	case "+":
-		return &object.Float{Value: leftVal + rightVal}
-	case "-":
-		return &object.Float{Value: leftVal - rightVal}
-	case "*":
-		return &object.Float{Value: leftVal * rightVal}
-	case "/":
-		return &object.Float{Value: leftVal / rightVal}
+	case "+", "-", "*", "/", "%", "**", "//":
+		return evalIntegerArithmetic(operator, leftVal, rightVal, checked)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -300,7 +485,7 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 	case ">=":
 		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -313,12 +498,8 @@ func evalBooleanInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
-	case "&&":
-		return nativeBoolToBooleanObject(leftVal && rightVal)
-	case "||":
-		return nativeBoolToBooleanObject(leftVal || rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -336,6 +517,41 @@ func evalIfExpression(ie *ast.ConditionalExpression, env *object.Environment) ob
 	}
 }
 
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Body, env)
+
+	err, ok := result.(*object.Error)
+	if !ok {
+		return result
+	}
+
+	if te.Variable != nil {
+		env.Set(te.Variable.Value, &object.Exception{Err: err})
+	}
+
+	return Eval(te.Handler, env)
+}
+
+// shortCircuitOperators only evaluate their right operand when the left one
+// does not decide the result, which is whichever operand was evaluated last
+var shortCircuitOperators = map[string]bool{
+	"&&": true,
+	"||": true,
+	"??": true,
+}
+
+// shortCircuits reports whether left alone decides the result of operator
+func shortCircuits(operator string, left object.Object) bool {
+	switch operator {
+	case "&&":
+		return !isTruthy(left)
+	case "||":
+		return isTruthy(left)
+	default:
+		return left != NULL
+	}
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -365,11 +581,50 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return val
 	}
 
-	if val, ok := builtins[node.Value]; ok {
+	if val, ok := lookupBuiltin(node.Value, env); ok {
 		return val
 	}
 
-	return newError("identifier not found: %s", node.Value)
+	return newNameError("identifier not found: %s", node.Value)
+}
+
+// evalImport loads the module at path and binds it to name
+func evalImport(path, name string, env *object.Environment) object.Object {
+	module := importModule(path, env)
+	if isError(module) {
+		return module
+	}
+
+	env.Set(name, module)
+	return NULL
+}
+
+// importModule loads the module at path through the importer of the current
+// file
+func importModule(path string, env *object.Environment) object.Object {
+	file, importer := env.Module()
+	if importer == nil {
+		return newTypeError("import is not supported here")
+	}
+
+	return importer.Import(path, file)
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Module:
+		if val, ok := obj.Env.Get(name); ok {
+			return val
+		}
+		return newNameError("module %s has no member %s", obj.Name, name)
+	case *object.Exception:
+		if val, ok := obj.Member(name); ok {
+			return val
+		}
+		return newNameError("exception has no member %s", name)
+	default:
+		return newTypeError("member access not supported: %s", obj.Type())
+	}
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
@@ -386,16 +641,42 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// newCallContext lets builtins called from env call back into the tree walker
+// and use the streams of the program
+func newCallContext(env *object.Environment) *object.CallContext {
+	return &object.CallContext{
+		Call: func(fn object.Object, args ...object.Object) object.Object {
+			return applyFunction(fn, args, env)
+		},
+		Streams: env.Streams(),
+	}
+}
+
+// applyFunction calls fn with args, env is where the call is made from
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) < len(fn.Parameters) {
+			return newArgumentError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+		}
+
+		depth := env.CallDepth() + 1
+		if depth > MaxCallDepth {
+			return newStackOverflowError("stack overflow")
+		}
+
 		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv.SetCallDepth(depth)
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		result := fn.Fn(newCallContext(env), args...)
+		if result == nil {
+			return NULL
+		}
+		return result
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newTypeError("not a function: %s", fn.Type())
 	}
 }
 
@@ -410,8 +691,11 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+	switch obj := obj.(type) {
+	case *object.ReturnValue:
+		return obj.Value
+	case *object.Break, *object.Continue:
+		return loopControlError(obj)
 	}
 	return obj
 }
@@ -420,10 +704,12 @@ func evalIndexExpression(array, index object.Object) object.Object {
 	switch {
 	case array.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(array, index)
-	case array.Type() == object.HASH_OBJ && index.(object.Hashable) != nil:
+	case array.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(array, index)
+	case array.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(array, index)
 	default:
-		return newError("index operator not supported: %s", array.Type())
+		return newTypeError("index operator not supported: %s", array.Type())
 	}
 }
 
@@ -433,20 +719,36 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	max := int64(len(arrayObject.Elements))
 
 	if idx == 0 {
-		return newError("come on, you know arrays are 1-indexed")
+		return newIndexError("come on, you know arrays are 1-indexed")
 	}
 
 	if idx < 1 || idx > max {
-		return newError("index out of bounds")
+		return newIndexError("index out of bounds")
 	}
 
 	return arrayObject.Elements[idx-1]
 }
 
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	chars := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	max := int64(len(chars))
+
+	if idx == 0 {
+		return newIndexError("come on, you know strings are 1-indexed")
+	}
+
+	if idx < 1 || idx > max {
+		return newIndexError("index out of bounds")
+	}
+
+	return &object.String{Value: string(chars[idx-1])}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.OrderedKeys() {
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -454,30 +756,29 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newKeyError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newKeyError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key.HashKey())
 	if !ok {
 		return NULL
 	}
@@ -485,53 +786,115 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
+func evalAssignTarget(node *ast.AssignExpression, value object.Object, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return evalAssignExpression(target.Value, node.Operator, value, env)
+	case *ast.IndexExpression:
+		container := Eval(target.Array, env)
+		if isError(container) {
+			return container
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexAssignExpression(container, index, node.Operator, value, env.CheckedArithmetic())
+	default:
+		return newError("invalid assignment target")
+	}
+}
+
 func evalAssignExpression(name, operator string, value object.Object, env *object.Environment) object.Object {
-	if _, ok := env.Get(name); !ok {
-		return newError("identifier not found: %s", name)
+	current, ok := env.Get(name)
+	if !ok {
+		return newNameError("identifier not found: %s", name)
 	}
 
-	switch operator {
-	case "=":
-		env.Set(name, value)
-	case "+=":
-		current, _ := env.Get(name)
-		if current.Type() != value.Type() {
-			return newError("type mismatch: %s += %s", current.Type(), value.Type())
-		}
-		env.Set(name, evalInfixExpression("+", current, value))
-	case "-=":
-		current, _ := env.Get(name)
-		if current.Type() != value.Type() {
-			return newError("type mismatch: %s -= %s", current.Type(), value.Type())
-		}
-		env.Set(name, evalInfixExpression("-", current, value))
-	case "*=":
-		current, _ := env.Get(name)
-		if current.Type() != value.Type() {
-			return newError("type mismatch: %s *= %s", current.Type(), value.Type())
-		}
-		env.Set(name, evalInfixExpression("*", current, value))
-	case "/=":
-		current, _ := env.Get(name)
-		if current.Type() != value.Type() {
-			return newError("type mismatch: %s /= %s", current.Type(), value.Type())
-		}
-		env.Set(name, evalInfixExpression("/", current, value))
+	result := evalAssignOperator(operator, current, value, env.CheckedArithmetic())
+	if isError(result) {
+		return result
+	}
+	env.Set(name, result)
+
+	return NULL
+}
+
+// evalIndexAssignExpression updates an element of an array or a hash in
+// place, assigning to a missing hash key inserts it
+func evalIndexAssignExpression(container, index object.Object, operator string, value object.Object, checked bool) object.Object {
+	switch container := container.(type) {
+	case *object.Array:
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return newTypeError("array index must be INTEGER, got %s", index.Type())
+		}
+
+		idx := integer.Value
+		if idx == 0 {
+			return newIndexError("come on, you know arrays are 1-indexed")
+		}
+		if idx < 1 || idx > int64(len(container.Elements)) {
+			return newIndexError("index out of bounds")
+		}
+
+		result := evalAssignOperator(operator, container.Elements[idx-1], value, checked)
+		if isError(result) {
+			return result
+		}
+		container.Elements[idx-1] = result
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newKeyError("unusable as hash key: %s", index.Type())
+		}
+
+		result := value
+		if operator != "=" {
+			pair, ok := container.Get(key.HashKey())
+			if !ok {
+				return newKeyError("key not found: %s", index.Inspect())
+			}
+
+			result = evalAssignOperator(operator, pair.Value, value, checked)
+			if isError(result) {
+				return result
+			}
+		}
+		container.Set(key.HashKey(), object.HashPair{Key: index, Value: result})
 	default:
-		return newError("unknown operator: %s", operator)
+		return newTypeError("index assignment not supported: %s", container.Type())
 	}
 
 	return NULL
 }
 
-func convertIntegerObjectToString(obj object.Object) object.Object {
-	return &object.String{Value: fmt.Sprintf("%d", obj.(*object.Integer).Value)}
+var assignOperators = map[string]string{
+	"+=": "+",
+	"-=": "-",
+	"*=": "*",
+	"/=": "/",
 }
 
-func convertFloatObjectToString(obj object.Object) object.Object {
-	return &object.String{Value: fmt.Sprintf("%f", obj.(*object.Float).Value)}
+// evalAssignOperator returns the value a target holds once value has been
+// assigned to it with operator
+func evalAssignOperator(operator string, current, value object.Object, checked bool) object.Object {
+	if operator == "=" {
+		return value
+	}
+
+	infix, ok := assignOperators[operator]
+	if !ok {
+		return newTypeError("unknown operator: %s", operator)
+	}
+
+	if current.Type() != value.Type() && promotedRank(current, value) == 0 {
+		return newTypeError("type mismatch: %s %s %s", current.Type(), operator, value.Type())
+	}
+
+	return evalInfixExpression(infix, current, value, checked)
 }
 
-func convertFloatToInteger(obj object.Object) object.Object {
-	return &object.Integer{Value: int64(obj.(*object.Float).Value)}
+func convertFloatObjectToString(obj object.Object) object.Object {
+	return &object.String{Value: fmt.Sprintf("%f", obj.(*object.Float).Value)}
 }
@@ -9,30 +9,32 @@ import (
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{"5", 5},
-		{"10", 10},
-		{"-5", -5},
-		{"--10", 10},
-		{"5 + 5 + 5 + 5 - 10", 10},
-		{"2 * 2 * 2 * 2 * 2", 32},
-		{"-50 + 100 + -50", 0},
-		{"5 * 2 + 10", 20},
-		{"5 + 2 * 10", 25},
-		{"20 + 2 * -10", 0},
-		{"50 / 2 * 2 + 10", 60},
-		{"2 * {5 + 10}", 30},
-		{"3 * 3 * 3 + 10", 37},
-		{"3 * {3 * 3} + 10", 37},
-		{"{5 + 10 * 2 + 15 / 3} * 2 + -10", 50},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testIntegerObject(t, evaluated, tt.expected)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{"5", 5},
+			{"10", 10},
+			{"-5", -5},
+			{"--10", 10},
+			{"5 + 5 + 5 + 5 - 10", 10},
+			{"2 * 2 * 2 * 2 * 2", 32},
+			{"-50 + 100 + -50", 0},
+			{"5 * 2 + 10", 20},
+			{"5 + 2 * 10", 25},
+			{"20 + 2 * -10", 0},
+			{"50 / 2 * 2 + 10", 60},
+			{"2 * {5 + 10}", 30},
+			{"3 * 3 * 3 + 10", 37},
+			{"3 * {3 * 3} + 10", 37},
+			{"{5 + 10 * 2 + 15 / 3} * 2 + -10", 50},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -49,29 +51,31 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 }
 
 func TestEvalFloatExpression(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected float64
-	}{
-		{"5.5", 5.5},
-		{"10.5", 10.5},
-		{"-5.5", -5.5},
-		{"--10.5", 10.5},
-		{"5.5 + 5.5 + 5.5 + 5.5 - 10.5", 11.5},
-		{"2.5 * 2.5 * 2.5 * 2.5 * 2.5", 97.65625},
-		{"-50.5 + 100.5 + -50.5", -0.5},
-		{"5.5 * 2.5 + 10.5", 24.25},
-		{"5.5 + 2.5 * 10.5", 31.75},
-		{"20.5 + 2.5 * -10.5", -5.75},
-		{"50.5 / 2.5 * 2.5 + 10.5", 61},
-		{"2.5 * {5.5 + 10.5}", 40},
-		{"3.5 * 3.5 * 3.5 + 10.5", 53.375},
-		{"3.5 * {3.5 * 3.5} + 10.5", 53.375},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testFloatObject(t, evaluated, tt.expected)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected float64
+		}{
+			{"5.5", 5.5},
+			{"10.5", 10.5},
+			{"-5.5", -5.5},
+			{"--10.5", 10.5},
+			{"5.5 + 5.5 + 5.5 + 5.5 - 10.5", 11.5},
+			{"2.5 * 2.5 * 2.5 * 2.5 * 2.5", 97.65625},
+			{"-50.5 + 100.5 + -50.5", -0.5},
+			{"5.5 * 2.5 + 10.5", 24.25},
+			{"5.5 + 2.5 * 10.5", 31.75},
+			{"20.5 + 2.5 * -10.5", -5.75},
+			{"50.5 / 2.5 * 2.5 + 10.5", 61},
+			{"2.5 * {5.5 + 10.5}", 40},
+			{"3.5 * 3.5 * 3.5 + 10.5", 53.375},
+			{"3.5 * {3.5 * 3.5} + 10.5", 53.375},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testFloatObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
 func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
@@ -88,44 +92,46 @@ func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
 }
 
 func TestEvalBooleanExpression(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected bool
-	}{
-		{"true", true},
-		{"false", false},
-		{"1 < 2", true},
-		{"1 > 2", false},
-		{"1 < 1", false},
-		{"1 > 1", false},
-		{"1 == 1", true},
-		{"1 != 1", false},
-		{"1 == 2", false},
-		{"1 != 2", true},
-		{"true == true", true},
-		{"false == false", true},
-		{"true == false", false},
-		{"true != false", true},
-		{"false != true", true},
-		{"{1 < 2} == true", true},
-		{"{1 < 2} == false", false},
-		{"{1 > 2} == true", false},
-		{"{1 > 2} == false", true},
-		{"{1 < 2} == {1 > 2}", false},
-		{"{1 < 2} != {1 > 2}", true},
-		{"{1 < 2} == {1 < 2}", true},
-		{"{1 < 2} != {1 < 2}", false},
-		{"{1 > 2} == {1 > 2}", true},
-		{"{1 > 2} != {1 > 2}", false},
-		{"2 % 2 == 0", true},
-		{"2 % 2 != 0", false},
-		{"1 <= 2", true},
-		{"2 <= 2", true},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testBooleanObject(t, evaluated, tt.expected)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected bool
+		}{
+			{"true", true},
+			{"false", false},
+			{"1 < 2", true},
+			{"1 > 2", false},
+			{"1 < 1", false},
+			{"1 > 1", false},
+			{"1 == 1", true},
+			{"1 != 1", false},
+			{"1 == 2", false},
+			{"1 != 2", true},
+			{"true == true", true},
+			{"false == false", true},
+			{"true == false", false},
+			{"true != false", true},
+			{"false != true", true},
+			{"{1 < 2} == true", true},
+			{"{1 < 2} == false", false},
+			{"{1 > 2} == true", false},
+			{"{1 > 2} == false", true},
+			{"{1 < 2} == {1 > 2}", false},
+			{"{1 < 2} != {1 > 2}", true},
+			{"{1 < 2} == {1 < 2}", true},
+			{"{1 < 2} != {1 < 2}", false},
+			{"{1 > 2} == {1 > 2}", true},
+			{"{1 > 2} != {1 > 2}", false},
+			{"2 % 2 == 0", true},
+			{"2 % 2 != 0", false},
+			{"1 <= 2", true},
+			{"2 <= 2", true},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testBooleanObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
@@ -142,48 +148,52 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 }
 
 func TestBangOperator(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected bool
-	}{
-		{"!true", false},
-		{"!false", true},
-		{"!!true", true},
-		{"!!false", false},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testBooleanObject(t, evaluated, tt.expected)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected bool
+		}{
+			{"!true", false},
+			{"!false", true},
+			{"!!true", true},
+			{"!!false", false},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testBooleanObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
 func TestIfElseExpressions(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected interface{}
-	}{
-		{"{true} if [ 10 ]", 10},
-		{"{false} if [ 10 ]", nil},
-		{"{1} if [ 10 ]", 10},
-		{"{1 < 2} if [ 10 ]", 10},
-		{"{1 > 2} if [ 10 ]", nil},
-		{"{1 > 2} if [ 10 ] else [ 20 ]", 20},
-		{"{1 < 2} if [ 10 ] else [ 20 ]", 10},
-		{"{1 > 2} if [ 10 ] {1 == 2} if else [ 20 ] else [ 30 ]", 30},
-		{"{1 > 2} if [ 10 ] {1 < 2} if else [ 20 ] else [ 30 ]", 20},
-		{"{1 < 2} if [ 10 ] {1 > 2} if else [ 20 ] else [ 30 ]", 10},
-		{"{1 < 2} if [ 10 ] {1 < 2} if else [ 20 ] else [ 30 ]", 10},
-		{"{1 > 2} if [ 10 ] {1 > 2} if else [ 20 ]", nil},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
-			testNullObject(t, evaluated)
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"{true} if [ 10 ]", 10},
+			{"{false} if [ 10 ]", nil},
+			{"{1} if [ 10 ]", 10},
+			{"{1 < 2} if [ 10 ]", 10},
+			{"{1 > 2} if [ 10 ]", nil},
+			{"{1 > 2} if [ 10 ] else [ 20 ]", 20},
+			{"{1 < 2} if [ 10 ] else [ 20 ]", 10},
+			{"{1 > 2} if [ 10 ] {1 == 2} if else [ 20 ] else [ 30 ]", 30},
+			{"{1 > 2} if [ 10 ] {1 < 2} if else [ 20 ] else [ 30 ]", 20},
+			{"{1 < 2} if [ 10 ] {1 > 2} if else [ 20 ] else [ 30 ]", 10},
+			{"{1 < 2} if [ 10 ] {1 < 2} if else [ 20 ] else [ 30 ]", 10},
+			{"{1 > 2} if [ 10 ] {1 > 2} if else [ 20 ]", nil},
 		}
-	}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			integer, ok := tt.expected.(int)
+			if ok {
+				testIntegerObject(t, evaluated, int64(integer))
+			} else {
+				testNullObject(t, evaluated)
+			}
+		}
+	})
 }
 
 func testNullObject(t *testing.T, obj object.Object) bool {
@@ -195,39 +205,42 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 }
 
 func TestReturnStatements(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{",10 return", 10},
-		{",2 * 5 return", 10},
-		{"9, 2 * 5 return", 10},
-		{"{10 > 1} if [,10 return ]", 10},
-		{"{10 > 1} if [,10 return ] else [,20 return ]", 10},
-		{`{10 > 1} if [
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{",10 return", 10},
+			{",2 * 5 return", 10},
+			{"9, 2 * 5 return", 10},
+			{"{10 > 1} if [,10 return ]", 10},
+			{"{10 > 1} if [,10 return ] else [,20 return ]", 10},
+			{`{10 > 1} if [
 			{10 > 1} if [
 				,10 return
 			] 
 			,20 return
 		]`,
-			10},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testIntegerObject(t, evaluated, tt.expected)
-	}
+				10},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
 func TestErrorHandling(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"5 + true", "type mismatch: INTEGER + BOOLEAN"},
-		{"-true", "unknown operator: -BOOLEAN"},
-		{"true + false", "unknown operator: BOOLEAN + BOOLEAN"},
-		{"{10 > 1} if [ true + false ]", "unknown operator: BOOLEAN + BOOLEAN"},
-		{`
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"5 + true", "type mismatch: INTEGER + BOOLEAN"},
+			{"-true", "unknown operator: -BOOLEAN"},
+			{"true + false", "unknown operator: BOOLEAN + BOOLEAN"},
+			{"{10 > 1} if [ true + false ]", "unknown operator: BOOLEAN + BOOLEAN"},
+			{`
 		{10 > 1} if [
 			{10 > 1} if [
 				,true + false return
@@ -235,36 +248,39 @@ func TestErrorHandling(t *testing.T) {
 			,10 return
 		]
 		`, "unknown operator: BOOLEAN + BOOLEAN"},
-		{"foobar", "identifier not found: foobar"},
-		{`$Hello$ - $World$`, "unknown operator: STRING - STRING"},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		errObj, ok := evaluated.(*object.Error)
-		if !ok {
-			t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
-			continue
+			{"foobar", "identifier not found: foobar"},
+			{`$Hello$ - $World$`, "unknown operator: STRING - STRING"},
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+			}
 		}
-	}
+	})
 }
 
 func TestLetStatements(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{",5 = a let\n a", 5},
-		{",5 * 5 = a let\na", 25},
-		{",5 = a let\n,a = b let\n b", 5},
-		{",5 = a let\n,a = b let\n,a + b + 5 = c let\n c", 15},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testIntegerObject(t, evaluated, tt.expected)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{",5 = a let\n a", 5},
+			{",5 * 5 = a let\na", 25},
+			{",5 = a let\n,a = b let\n b", 5},
+			{",5 = a let\n,a = b let\n,a + b + 5 = c let\n c", 15},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
 func TestFunctionObject(t *testing.T) {
@@ -294,237 +310,1092 @@ func TestFunctionObject(t *testing.T) {
 }
 
 func TestFunctionApplication(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected int64
-	}{
-		{"{x} abc func [x + 2]\n{2}abc", 4},
-		{"{x} abc func [x + 2]\n{5}abc", 7},
-		{"{x} abc func [x + 2]\n{5 * 5}abc", 27},
-		{"{a; b} add func [a + b]\n{2; 3}add", 5},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testIntegerObject(t, evaluated, tt.expected)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{"{x} abc func [x + 2]\n{2}abc", 4},
+			{"{x} abc func [x + 2]\n{5}abc", 7},
+			{"{x} abc func [x + 2]\n{5 * 5}abc", 27},
+			{"{a; b} add func [a + b]\n{2; 3}add", 5},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		}
+	})
 }
 
-func TestStringLiteral(t *testing.T) {
-	input := `$Hello World!$`
-	evaluated := utils.EvalTest(input)
-	str, ok := evaluated.(*object.String)
-	if !ok {
-		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
-	}
-	if str.Value != "Hello World!" {
-		t.Errorf("String has wrong value. got=%q", str.Value)
-	}
+func TestFunctionLiteral(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{",{x} func [ ,x * 2 return ] = double let\n,{4}double", 8},
+			{"{f; x} apply func [ ,{x}f return ]\n,{{x} func [ x + 1 ]; 41}apply", 42},
+			{",(1; {x} func [ ,x * 3 return ]) = fns let\n,(2)fns = triple let\n,{5}triple", 15},
+			{"{n} adder func [ ,{x} func [ ,x + n return ] return ]\n,{2}adder = addTwo let\n,{40}addTwo", 42},
+			{"{f; x} twice func [ ,{{x}f}f return ]\n,{{x} func [ ,x * 3 return ]; 2}twice", 18},
+		}
+
+		for _, tt := range tests {
+			testIntegerObject(t, eval(tt.input), tt.expected)
+		}
+	})
 }
 
-func TestStringConcatenation(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{`$Hello$ + $ $ + $World!$`, "Hello World!"},
-		{`$Hello$ + 1 + $World!$`, "Hello1World!"},
-		{`$Hello$ + 1`, "Hello1"},
-		{`1 + $Hello$`, "1Hello"},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
+func TestStringLiteral(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := `$Hello World!$`
+		evaluated := eval(input)
 		str, ok := evaluated.(*object.String)
 		if !ok {
 			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
 		}
-		if str.Value != tt.expected {
+		if str.Value != "Hello World!" {
 			t.Errorf("String has wrong value. got=%q", str.Value)
 		}
-	}
+	})
+}
+
+func TestStringConcatenation(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`$Hello$ + $ $ + $World!$`, "Hello World!"},
+			{`$Hello$ + 1 + $World!$`, "Hello1World!"},
+			{`$Hello$ + 1`, "Hello1"},
+			{`1 + $Hello$`, "1Hello"},
+			{`$costs \$5\n$ + $$$C:\new$$$`, "costs $5\nC:\\new"},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			}
+			if str.Value != tt.expected {
+				t.Errorf("String has wrong value. got=%q", str.Value)
+			}
+		}
+	})
+}
+
+func TestStringComparison(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected bool
+		}{
+			{`$abc$ == $abc$`, true},
+			{`$abc$ == $abd$`, false},
+			{`$abc$ != $abd$`, true},
+			{`$abc$ < $abd$`, true},
+			{`$b$ > $abc$`, true},
+			{`$a$ <= $a$`, true},
+			{`$a$ >= $b$`, false},
+			{`$a$ + $b$ == $ab$`, true},
+			{`$1$ == 1`, false},
+		}
+		for _, tt := range tests {
+			testBooleanObject(t, eval(tt.input), tt.expected)
+		}
+	})
+}
+
+func TestStringIndexExpressions(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`(1)$abc$`, "a"},
+			{",$abc$ = s let\n,(3)s", "c"},
+			{",$abc$ = s let\n,({s}len)s", "c"},
+			{`(0)$abc$`, "ERROR: come on, you know strings are 1-indexed"},
+			{`(4)$abc$`, "ERROR: index out of bounds"},
+			{`$1$ < 2`, "ERROR: type mismatch: STRING < INTEGER"},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
+
+func TestStringBuiltins(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`{$a,b,c$; $,$}split`, "(a; b; c)"},
+			{`{(1; $b$; true); $-$}join`, "1-b-true"},
+			{`{($a$; $b$)}join`, "ab"},
+			{`{$  hi  $}trim`, "hi"},
+			{`{$Hi$}upper`, "HI"},
+			{`{$Hi$}lower`, "hi"},
+			{`{$a-b-c$; $-$; $+$}replace`, "a+b+c"},
+			{`{$hello$; $ell$}contains`, "true"},
+			{`{$hello$; $he$}startsWith`, "true"},
+			{`{$hello$; $he$}endsWith`, "false"},
+			{`{$hello$; $l$}indexOf`, "3"},
+			{`{$hello$; $z$}indexOf`, "0"},
+			{`{$hello$; 2; 3}substr`, "ell"},
+			{`{$hello$; 2}substr`, "ello"},
+			{`{$hello$; 4; 10}substr`, "lo"},
+			{`{$hello$; 6}substr`, ""},
+			{`{$ab$; 3}repeat`, "ababab"},
+			{`{$abc$}chars`, "(a; b; c)"},
+			{`{$hello$; 0}substr`, "ERROR: index out of bounds"},
+			{`{$ab$; -1}repeat`, "ERROR: count must not be negative"},
+			{`{$hello$; 2; 9223372036854775807}substr`, "ello"},
+			{`{$ab$; 9223372036854775807}repeat`, "ERROR: repeated string too long: 9223372036854775807 times 2 bytes"},
+			{`{$$; 9223372036854775807}repeat`, ""},
+			{`try [ ,{$ab$; 9223372036854775807}repeat ] {e} catch [ ,e.kind ]`, "ValueError"},
+			{`{$abc$; 1}split`, "ERROR: second argument to `split` must be STRING, got INTEGER"},
+			{`{$abc$}contains`, "ERROR: wrong number of arguments. got=1, want=2"},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
 }
 
 func TestBuiltInFunction(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected interface{}
-	}{
-		{"{$$}len", 0},
-		{"{$hello$}len", 5},
-		{"{$hello world$}len", 11},
-		{"{2}len", "argument to `len` not supported, got INTEGER"},
-		{"{true}len", "argument to `len` not supported, got BOOLEAN"},
-		{"{$abc$; $def$}len", "wrong number of arguments. got=2, want=1"},
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"{$$}len", 0},
+			{"{$hello$}len", 5},
+			{"{$hello world$}len", 11},
+			{"{2}len", "argument to `len` not supported, got INTEGER"},
+			{"{true}len", "argument to `len` not supported, got BOOLEAN"},
+			{"{$abc$; $def$}len", "wrong number of arguments. got=2, want=1"},
+		}
 
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
 
-		switch expected := tt.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(expected))
-		case string:
-			errObj, ok := evaluated.(*object.Error)
-			if !ok {
-				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
-				continue
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+					continue
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
 			}
-			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		}
+	})
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{",{(1; 2; 3); {x} func [ ,x * 2 return ]}map", "(2; 4; 6)"},
+			{",{(); {x} func [ ,x * 2 return ]}map", "()"},
+			{",{(1; 2; 3; 4); {x} func [ ,x % 2 == 0 return ]}filter", "(2; 4)"},
+			{",{(1; 2; 3; 4); {acc; x} func [ ,acc + x return ]}reduce", "10"},
+			{",{(1; 2; 3); {acc; x} func [ ,acc * x return ]; 10}reduce", "60"},
+			{",{(3; 1; 2)}sort", "(1; 2; 3)"},
+			{",{(1.5; 0.5; 1.0)}sort", "(0.5; 1; 1.5)"},
+			{",{(3; 1; 2); {a; b} func [ ,a > b return ]}sort", "(3; 2; 1)"},
+			{",{(1; 5; 7); {x} func [ ,x > 4 return ]}find", "5"},
+			{",{(1; 2); {x} func [ ,x > 4 return ]}find", "null"},
+			{",{(1; 5); {x} func [ ,x > 4 return ]}any", "true"},
+			{",{(1; 5); {x} func [ ,x > 4 return ]}all", "false"},
+			{",{(); {x} func [ ,x > 4 return ]}all", "true"},
+			{",{(1; 2; 3); ($a$; $b$)}zip", "((1; a); (2; b))"},
+			{",{4}range", "(1; 2; 3; 4)"},
+			{",{0}range", "()"},
+			{",{5; 1; -2}range", "(5; 3; 1)"},
+			{",{9223372036854775806; 9223372036854775807}range", "(9223372036854775806; 9223372036854775807)"},
+			{",{-9223372036854775807 - 1 + 1; -9223372036854775807 - 1}range", "(-9223372036854775807; -9223372036854775808)"},
+			{",{9223372036854775800; 9223372036854775807; 5}range", "(9223372036854775800; 9223372036854775805)"},
+			{",{0; 9223372036854775807; 9223372036854775807}range", "(0; 9223372036854775807)"},
+			{",{9223372036854775807; -9223372036854775807 - 1; -9223372036854775807 - 1}range", "(9223372036854775807; -1)"},
+			{",{(1; 2); len}map", "ERROR: argument to `len` not supported, got INTEGER"},
+			{",{(); {a; b} func [ a ]}reduce", "ERROR: `reduce` of empty array with no initial value"},
+			{",{(1; 2); 5}map", "ERROR: second argument to `map` must be FUNCTION, got INTEGER"},
+			{",{5; {x} func [ x ]}filter", "ERROR: first argument to `filter` must be ARRAY, got INTEGER"},
+			{",{(1; true)}sort", "ERROR: type mismatch: BOOLEAN < INTEGER"},
+			{",{(1; 2); {} func [ 1 ]}map", "(1; 1)"},
+			{",{(1; 2); {a; b} func [ a ]}map", "ERROR: wrong number of arguments. got=1, want=2"},
+			{"{n} scale func [ ,{(1; 2); {x} func [ ,x * n return ]}map return ]\n,{3}scale", "(3; 6)"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
 			}
 		}
-	}
+	})
+}
+
+func TestCallbackErrorPosition(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := `{arr} double func [
+	,{arr; {x} func [ ,x * y return ]}map return
+]
+,{(1; 2)}double`
+
+		errObj, ok := eval(input).(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned")
+		}
+
+		if errObj.Pos.String() != "2:25" {
+			t.Errorf("wrong position. expected=2:25, got=%s", errObj.Pos)
+		}
+
+		if len(errObj.Stack) != 1 || errObj.Stack[0].Function != "double" {
+			t.Errorf("wrong stack. got=%+v", errObj.Stack)
+		}
+	})
 }
 
 func TestArrayLiterals(t *testing.T) {
-	input := "(1; 2 * 2; 3 + 3)"
-	evaluated := utils.EvalTest(input)
-	result, ok := evaluated.(*object.Array)
-	if !ok {
-		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := "(1; 2 * 2; 3 + 3)"
+		evaluated := eval(input)
+		result, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+		}
 
-	if len(result.Elements) != 3 {
-		t.Fatalf("array has wrong number of elements. got=%d", len(result.Elements))
-	}
+		if len(result.Elements) != 3 {
+			t.Fatalf("array has wrong number of elements. got=%d", len(result.Elements))
+		}
 
-	testIntegerObject(t, result.Elements[0], 1)
-	testIntegerObject(t, result.Elements[1], 4)
-	testIntegerObject(t, result.Elements[2], 6)
+		testIntegerObject(t, result.Elements[0], 1)
+		testIntegerObject(t, result.Elements[1], 4)
+		testIntegerObject(t, result.Elements[2], 6)
+	})
 }
 
 func TestArrayIndexExpressions(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected interface{}
-	}{
-		{"(1)(1; 2; 3)", 1},
-		{"(2)(1; 2; 3)", 2},
-		{"(3)(1; 2; 3)", 3},
-		{"(1 + 1 + 1)(1; 2; 3)", 3},
-		{",(1; 2; 3) = myArray let\n(1)myArray", 1},
-		{",(1; 2; 3) = myArray let\n(2)myArray", 2},
-		{",(1; 2; 3) = myArray let\n(3)myArray", 3},
-	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"(1)(1; 2; 3)", 1},
+			{"(2)(1; 2; 3)", 2},
+			{"(3)(1; 2; 3)", 3},
+			{"(1 + 1 + 1)(1; 2; 3)", 3},
+			{",(1; 2; 3) = myArray let\n(1)myArray", 1},
+			{",(1; 2; 3) = myArray let\n(2)myArray", 2},
+			{",(1; 2; 3) = myArray let\n(3)myArray", 3},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
 
-		switch expected := tt.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(expected))
-		default:
-			testNullObject(t, evaluated)
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			default:
+				testNullObject(t, evaluated)
+			}
 		}
-	}
+	})
 }
 
 func TestArrayIndexOutOfBounds(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"(4)(1; 2; 3)", "index out of bounds"},
-		{"(0)(1; 2; 3)", "come on, you know arrays are 1-indexed"},
-		{"(-1)(1; 2; 3)", "index out of bounds"},
-	}
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"(4)(1; 2; 3)", "index out of bounds"},
+			{"(0)(1; 2; 3)", "come on, you know arrays are 1-indexed"},
+			{"(-1)(1; 2; 3)", "index out of bounds"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", "index out of bounds", errObj.Message)
+			}
+		}
+	})
+}
+
+func TestHashLiterals(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := "[1= 2; 2= 3]"
+		evaluated := eval(input)
+		result, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Fatalf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		expected := map[object.HashKey]int64{
+			(&object.Integer{Value: 1}).HashKey(): 2,
+			(&object.Integer{Value: 2}).HashKey(): 3,
+		}
+
+		if len(result.Pairs) != 2 {
+			t.Fatalf("hash has wrong number of pairs. got=%d", len(result.Pairs))
+		}
+
+		for expectedKey, expectedValue := range expected {
+			pair, ok := result.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+			}
+			testIntegerObject(t, pair.Value, expectedValue)
+		}
+	})
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"(1)[1= 2; 2= 3]", 2},
+			{"(2)[1= 2; 2= 3]", 3},
+			{"(1 + 1)[1= 2; 2= 3]", 3},
+			{",[1= 2; 2= 3] = myHash let\n(1)myHash", 2},
+			{",[1= 2; 2= 3] = myHash let\n(2)myHash", 3},
+			{",[1= 2; 2= 3] = myHash let\n(3)myHash", nil},
+			{"(1.0)[1= 2; 2= 3]", 2},
+			{"(2.00d)[1= 2; 2= 3]", 3},
+			{"(2)[2.0= 3]", 3},
+			{"(1.5)[1= 2; 2= 3]", nil},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			default:
+				testNullObject(t, evaluated)
+			}
+		}
+	})
+}
+
+func TestHashOrderAndKeys(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"[$c$ = 1; $a$ = 2; $b$ = 3]", "[c: 1; a: 2; b: 3]"},
+			{"[1.5 = $x$; 2 = $y$]", "[1.5: x; 2: y]"},
+			{"(1.5)[1.5 = $x$; 2 = $y$]", "x"},
+			{"(0.0)[-0.0 = $zero$]", "zero"},
+			{",$$ = s let\n{k; [$z$ = 1; $y$ = 2; $x$ = 3]} for [ ,s + k = s ]\ns", "zyx"},
+			{",(1; 2) = k let\n(k)[1 = 2]", "ERROR: unusable as hash key: ARRAY"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
+
+func TestHashBuiltins(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"{[$b$ = 1; $a$ = 2]}keys", "(b; a)"},
+			{"{[$b$ = 1; $a$ = 2]}values", "(1; 2)"},
+			{"{[$b$ = 1; $a$ = 2]}entries", "((b; 1); (a; 2))"},
+			{"{[$b$ = 1]; $b$}has", "true"},
+			{"{[$b$ = 1]; $c$}has", "false"},
+			{"{[$b$ = 1]; $a$; 2}set", "[b: 1; a: 2]"},
+			{"{[$b$ = 1; $a$ = 2]; $b$; 3}set", "[b: 3; a: 2]"},
+			{",[$b$ = 1] = h let\n,{h; $a$; 2}set\nh", "[b: 1]"},
+			{"{[$b$ = 1; $a$ = 2; $c$ = 3]; $a$}delete", "[b: 1; c: 3]"},
+			{"{[$b$ = 1]; $z$}delete", "[b: 1]"},
+			{"{[$a$ = 1; $b$ = 2]; [$b$ = 3; $c$ = 4]}merge", "[a: 1; b: 3; c: 4]"},
+			{"{(1; 2)}keys", "ERROR: first argument to `keys` must be HASH, got ARRAY"},
+			{"{[1 = 1]; (1)}has", "ERROR: unusable as hash key: ARRAY"},
+			{"{[1 = 1]; 2}merge", "ERROR: arguments to `merge` must be HASH, got INTEGER"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
+
+func TestFuncCallAssignment(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := `{a; b} add func [
+    ,a + b return
+]
 
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
+,{2; 4}add = res let
+res`
+		evaluated := eval(input)
+		testIntegerObject(t, evaluated, 6)
+	})
+}
+
+func TestReassignmentOperators(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{",5 = a let\n,5 += a\na", 10},
+			{",5 = a let\n,5 -= a\na", 0},
+			{",5 = a let\n,5 *= a\na", 25},
+			{",5 = a let\n,5 /= a\na", 1},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			testIntegerObject(t, evaluated, tt.expected)
+		}
+	})
+}
+
+// TestScopes checks that a function sets variables in its own scope while
+// reading the ones around it until it does, the vm resolves them to slots
+func TestScopes(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{",1 = x let\n{} f func [ ,x = y let ,2 = x let ,y * 10 + x return ]\n,{}f", "12"},
+			{",1 = x let\n{} f func [ ,5 = x ]\n,{}f\nx", "1"},
+			{"{} f func [ ,5 = nope ]\n,{}f", "ERROR: identifier not found: nope"},
+			{"{} counter func [ ,0 = n let ,{} func [ ,n + 1 = n ,n return ] return ]\n,{}counter = c let\n,{}c + {}c", "2"},
+			{"{} f func [ ,{} func [ ,v return ] = g let ,7 = v let ,{}g return ]\n,{}f", "7"},
+			{",3 = v let\n{} f func [ ,{} func [ ,v return ] = g let ,{}g = first let ,7 = v let ,first * 10 + {}g return ]\n,{}f", "37"},
+			{"{len} f func [ ,len return ]\n,{4}f", "4"},
+			{"{} f func [ ,{(1; 2)}len = a let {a > 5} if [ ,0 = len let ] ,a return ]\n,{}f", "2"},
+			{",100 = i let\n{} f func [ ,0 = s let {i; 1; 3} for [ ,i += s ] ,s return ]\n,{}f + i", "106"},
+			{",1 = e let\n{} f func [ try [ ,{$x$}raise ] {e} catch [ ] ,e.message return ]\n,{}f + e", "x1"},
+			{"{} f func [ {n} g func [ {n < 1} if [ ,0 return ] ,n + {n - 1}g return ] ,{4}g return ]\n,{}f", "10"},
+			{"{a; a} f func [ ,a return ]\n,{1; 2}f", "2"},
+			{",1.5 * 2.0 < 3.5", "true"},
+		}
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
+}
+
+func TestIndexAssignment(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{",(1; 2; 3) = arr let\n,5 = (2)arr\narr", "(1; 5; 3)"},
+			{",(1; 2; 3) = arr let\n,10 += (3)arr\narr", "(1; 2; 13)"},
+			{",(1; 2; 3) = arr let\n,arr = alias let\n,0 = (1)alias\narr", "(0; 2; 3)"},
+			{",((1; 2); (3; 4)) = grid let\n,9 = (1)(2)grid\ngrid", "((1; 2); (9; 4))"},
+			{",((1; 2); (3; 4)) = grid let\n,2 *= (2)(1)grid\n,(2)(1)grid", "4"},
+			{",[$a$ = 1] = m let\n,2 = ($b$)m\nm", "[a: 1; b: 2]"},
+			{",[$a$ = 1] = m let\n,5 -= ($a$)m\nm", "[a: -4]"},
+			{",[$a$ = (1; 2)] = m let\n,7 = (1)($a$)m\nm", "[a: (7; 2)]"},
+			{"{arr} reset func [ ,0 = (1)arr ]\n,(1; 2) = xs let\n,{xs}reset\nxs", "(0; 2)"},
+			{",(1; 2) = arr let\n,5 = (0)arr", "ERROR: come on, you know arrays are 1-indexed"},
+			{",(1; 2) = arr let\n,5 = (3)arr", "ERROR: index out of bounds"},
+			{",(1; 2) = arr let\n,5 = ($a$)arr", "ERROR: array index must be INTEGER, got STRING"},
+			{",[$a$ = 1] = m let\n,1 += ($b$)m", "ERROR: key not found: b"},
+			{",(1; 2) = arr let\n,$x$ += (1)arr", "ERROR: type mismatch: INTEGER += STRING"},
+			{",$abc$ = s let\n,$x$ = (1)s", "ERROR: index assignment not supported: STRING"},
+			{",5 = (1)nope", "ERROR: identifier not found: nope"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
+
+func TestWhileExpression(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := `,0 = x let
+
+{x < 5} while [
+	,1 += x
+]
+
+x
+`
+		evaluated := eval(input)
+		testIntegerObject(t, evaluated, 5)
+	})
+}
+
+func TestErrorPositions(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"foobar", "1:1"},
+			{",1 = a let\n,{a + x}print", "2:7"},
+			{",1 = a let\n,a + true = b let", "2:4"},
+			{"{x} f func [\n  ,x - $a$ return\n]\n,{1}f", "2:6"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Pos.String() != tt.expected {
+				t.Errorf("wrong error position for %q. expected=%s, got=%s", errObj.Message, tt.expected, errObj.Pos)
+			}
+		}
+	})
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		input := `{a} inner func [
+	,a + x return
+]
+
+{} outer func [
+	,{1}inner return
+]
+
+,{}outer`
+
+		evaluated := eval(input)
 		errObj, ok := evaluated.(*object.Error)
 		if !ok {
-			t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
 		}
-		if errObj.Message != tt.expected {
-			t.Errorf("wrong error message. expected=%q, got=%q", "index out of bounds", errObj.Message)
+
+		expected := []struct {
+			function string
+			pos      string
+		}{
+			{"inner", "6:6"},
+			{"outer", "9:4"},
 		}
-	}
+
+		if len(errObj.Stack) != len(expected) {
+			t.Fatalf("wrong number of frames. expected=%d, got=%d", len(expected), len(errObj.Stack))
+		}
+
+		for i, tt := range expected {
+			frame := errObj.Stack[i]
+			if frame.Function != tt.function {
+				t.Errorf("frame[%d] has wrong function. expected=%q, got=%q", i, tt.function, frame.Function)
+			}
+			if frame.Pos.String() != tt.pos {
+				t.Errorf("frame[%d] has wrong position. expected=%s, got=%s", i, tt.pos, frame.Pos)
+			}
+		}
+	})
 }
 
-func TestHashLiterals(t *testing.T) {
-	input := "[1= 2; 2= 3]"
-	evaluated := utils.EvalTest(input)
-	result, ok := evaluated.(*object.Hash)
-	if !ok {
-		t.Fatalf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+func forEachEngine(t *testing.T, fn func(t *testing.T, eval func(string) object.Object)) {
+	for _, name := range []string{"tree", "vm"} {
+		t.Run(name, func(t *testing.T) {
+			fn(t, utils.Engines[name])
+		})
 	}
+}
 
-	expected := map[object.HashKey]int64{
-		(&object.Integer{Value: 1}).HashKey(): 2,
-		(&object.Integer{Value: 2}).HashKey(): 3,
-	}
+func TestStackOverflow(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"{n} f func [ ,{n + 1}f return ]\n,{1}f", "ERROR: stack overflow"},
+			{"{n} f func [ ,{n + 1}f return ]\ntry [ ,{1}f ] {e} catch [ ,e.message ]", "stack overflow"},
+			{"{n} f func [ {n == 1023} if [ ,n return ] ,{n + 1}f return ]\n,{1}f", "1023"},
+			{"{n} f func [ {n == 1024} if [ ,n return ] ,{n + 1}f return ]\n,{1}f", "ERROR: stack overflow"},
+			{"{n} f func [ ,{(1); {x} func [ ,{n + 1}f return ]}map return ]\n,{1}f", "ERROR: stack overflow"},
+		}
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
+}
 
-	if len(result.Pairs) != 2 {
-		t.Fatalf("hash has wrong number of pairs. got=%d", len(result.Pairs))
+// forEachCheckedEngine is forEachEngine with checked arithmetic turned on
+func forEachCheckedEngine(t *testing.T, fn func(t *testing.T, eval func(string) object.Object)) {
+	for _, name := range []string{"tree", "vm"} {
+		t.Run(name, func(t *testing.T) {
+			fn(t, func(input string) object.Object {
+				env := object.NewEnvironment()
+				env.SetCheckedArithmetic(true)
+				return utils.EnvEngines[name](input, env)
+			})
+		})
 	}
+}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
-		if !ok {
-			t.Errorf("no pair for given key in Pairs")
+func TestBreakContinue(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{",0 = s let\n{i; 1; 10} for [ {i > 3} if [ ,break ] ,i += s ]\ns", 6},
+			{",0 = s let\n{i; 1; 10} for [ {i % 2 == 0} if [ ,continue ] ,i += s ]\ns", 25},
+			{",0 = i let\n{true} while [ ,1 += i {i == 5} if [ ,break ] ]\ni", 5},
+			{",0 = i let\n,0 = s let\n{i < 6} while [ ,1 += i {i == 3} if [ ,continue ] ,i += s ]\ns", 18},
+			{",0 = s let\n{i; 1; 3} for [ {j; 1; 3} for [ {j > i} if [ ,break ] ,1 += s ] ]\ns", 6},
+			{",0 = s let\n{i; 1; 5} for [ {i > 1} if [ {i < 5} if [ ,continue ] ] ,i += s ]\ns", 6},
+			{"{} f func [ {i; 1; 10} for [ {i == 4} if [ ,break ] ] ,i return ]\n,{}f", 4},
+			{",0 = s let\n{i; 1; 5} for [ {} g func [ ,1 return ] ,{}g += s {i == 2} if [ ,break ] ]\ns", 2},
+			{",0 = s let\n{i; 1; 5} for [ {j; 1; 2} for [ ,{(1); {a} func [ ,a ]}map ] ,1 += s {i == 2} if [ ,break ] ]\ns", 2},
+			{",0 = s let\n{i; 1; 5} for [ {j; 1; 2} for [ ,{(1); {a} func [ ,a ]}map ] {i < 3} if [ ,continue ] ,1 += s ]\ns", 3},
 		}
-		testIntegerObject(t, pair.Value, expectedValue)
+
+		for _, tt := range tests {
+			testIntegerObject(t, eval(tt.input), tt.expected)
+		}
+	})
+}
+
+func TestForExpression(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected interface{}
+		}{
+			{",0 = s let\n{x; (1; 2; 3)} for [ ,x += s ]\ns", 6},
+			{",0 = s let\n{x; ()} for [ ,x += s ]\ns", 0},
+			{",0 = s let\n{k; [1 = 10; 2 = 20]} for [ ,k += s ]\ns", 3},
+			{",$$ = s let\n{c; $abc$} for [ ,c + s = s ]\ns", "cba"},
+			{",0 = s let\n{i; 1; 5} for [ ,i += s ]\ns", 15},
+			{",0 = s let\n{i; 1; 10; 3} for [ ,i += s ]\ns", 22},
+			{",$$ = s let\n{i; 3; 1} for [ ,s + i = s ]\ns", "321"},
+			{",0 = s let\n{i; 1; 3} for [ ,i += s ]\ni", 3},
+			{",0 = n let\n{i; 9223372036854775806; 9223372036854775807} for [ ,1 += n ]\nn", 2},
+			{"{arr} f func [ {x; arr} for [ {x > 1} if [ ,x return ] ] ,0 return ]\n,{(1; 5; 7)}f", 5},
+			{"{x; 5} for [ x ]", "for expects ARRAY, HASH or STRING, got INTEGER"},
+			{"{i; 1; $a$} for [ i ]", "range bounds must be INTEGER, got STRING"},
+			{"{i; 1; 5; 0} for [ i ]", "range step must not be 0"},
+			{"{x; (1; 2)} for [ x + true ]", "type mismatch: INTEGER + BOOLEAN"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				switch result := evaluated.(type) {
+				case *object.String:
+					if result.Value != expected {
+						t.Errorf("String has wrong value. expected=%q, got=%q", expected, result.Value)
+					}
+				case *object.Error:
+					if result.Message != expected {
+						t.Errorf("wrong error message. expected=%q, got=%q", expected, result.Message)
+					}
+				default:
+					t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+				}
+			}
+		}
+	})
+}
+
+func TestTryCatch(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"try [ ,1 + 1 ] {e} catch [ ,0 ]", "2"},
+			{"try [ ,(5)(1; 2) ] {e} catch [ ,e ]", "IndexError: index out of bounds"},
+			{"try [ ,1 + true ] {e} catch [ ,e.kind ]", "TypeError"},
+			{"try [ ,nope ] {e} catch [ ,e.message ]", "identifier not found: nope"},
+			{"try [ ,{1; 2}len ] {e} catch [ ,e.kind ]", "ArgumentError"},
+			{"try [ ,{$boom$}raise ] {e} catch [ ,e ]", "Error: boom"},
+			{"try [ ,{$boom$; $ValueError$}raise ] {e} catch [ ,e.kind ]", "ValueError"},
+			// the kind comes from where the error is raised, not from its message
+			{"try [ ,{$type mismatch$}raise ] {e} catch [ ,e.kind ]", "Error"},
+			{"try [ ,1 / 0 ] {e} catch [ ,e.kind ]", "ZeroDivisionError"},
+			{"try [ ,{$x$}decimal ] {e} catch [ ,e.kind ]", "ValueError"},
+			{"try [ ,{[1 = 1]; (1)}has ] {e} catch [ ,e.kind ]", "KeyError"},
+			{"try [ ,{} f func [ ,{}f return ] ,{}f ] {e} catch [ ,e.kind ]", "StackOverflowError"},
+			{"try [ ,{(); {a; b} func [ ,a ]}reduce ] {e} catch [ ,e.kind ]", "Error"},
+			{"\n  try [ ,{$boom$}raise ] {e} catch [ ,(e.line; e.column) ]", "(2; 18)"},
+			{"try [ ,{$boom$}raise ] {} catch [ ,$ignored$ ]", "ignored"},
+			{",try [ ,{2}raise ] {e} catch [ ,e.message ] = m let\nm", "first argument to `raise` must be STRING or EXCEPTION, got INTEGER"},
+			{"try [ ,{$a$}raise ] {e} catch [ ,e.stack ]", "ERROR: exception has no member stack"},
+			{"try [ ,{$inner$}raise ] {e} catch [ ,{e}raise ]", "ERROR: inner"},
+			{"try [ try [ ,{$inner$}raise ] {e} catch [ ,{e}raise ] ] {e} catch [ ,e.message ]", "inner"},
+			{"try [ ,{$a$}raise ] {e} catch [ ,{$b$}raise ]", "ERROR: b"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
+
+func TestTryCatchAcrossCalls(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			// errors unwind through functions to the closest try
+			{"{x} check func [ {x < 0} if [ ,{$negative$}raise ] ,x return ]\ntry [ ,{-1}check ] {e} catch [ ,e.message ]", "negative"},
+			// a return leaves the try block of the function behind
+			{"{} f func [ try [ ,1 return ] {} catch [ ,2 ] ]\n,{}f\n,{$after$}raise", "ERROR: after"},
+			// so do break and continue
+			{",0 = s let\n{i; 1; 5} for [ try [ {i == 2} if [ ,continue ] {i == 4} if [ ,break ] ,i += s ] {} catch [ ,0 ] ]\ntry [ ,{$x$}raise ] {} catch [ ,s ]", "4"},
+			{",0 = s let\n{i; (1; 2)} for [ try [ ,{(1; 2); {a} func [ ,a ]}map ] {e} catch [ ,1 ] ,1 += s ,break ]\ntry [ ,{$x$}raise ] {} catch [ ,s ]", "1"},
+			// callbacks of builtins
+			{"{(1; 0; 2); {x} func [ ,try [ {x == 0} if [ ,{$zero$}raise ] ,x return ] {e} catch [ ,e.message return ] ]}map", "(1; zero; 2)"},
+			{"try [ ,{(1; 2); {x} func [ ,{$bad$}raise ]}map ] {e} catch [ ,e.message ]", "bad"},
+			// the handler runs in the scope of the try
+			{",0 = n let\ntry [ ,{$a$}raise ] {} catch [ ,1 += n ]\nn", "1"},
+		}
+
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
+
+func TestDivisionByZero(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []string{
+			"1 / 0",
+			"1 % 0",
+			"1.5 / 0.0",
+			",1 = x let\n,0 /= x",
+		}
+
+		for _, input := range tests {
+			evaluated := eval(input)
+			if evaluated.Inspect() != "ERROR: division by zero" {
+				t.Errorf("%q: expected division by zero, got=%q", input, evaluated.Inspect())
+			}
+		}
+
+		evaluated := eval("try [ ,10 % 0 ] {e} catch [ ,e.kind ]")
+		if evaluated.Inspect() != "ZeroDivisionError" {
+			t.Errorf("wrong kind. got=%q", evaluated.Inspect())
+		}
+	})
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		input     string
+		unchecked string
+		checked   string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "-9223372036854775809", "ERROR: integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "9223372036854775808", "ERROR: integer overflow: 4611686018427387904 * 2"},
+		{",-9223372036854775807 - 1 = min let\n,min * -1", "9223372036854775808", "ERROR: integer overflow: -9223372036854775808 * -1"},
+		{",-9223372036854775807 - 1 = min let\n,min / -1", "9223372036854775808", "ERROR: integer overflow: -9223372036854775808 / -1"},
+		{",-9223372036854775807 - 1 = min let\n,-min", "9223372036854775808", "ERROR: integer overflow: -(-9223372036854775808)"},
+		{",9223372036854775807 = max let\n,1 += max\nmax", "9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"{x} f func [ ,x + 1 return ]\n,{9223372036854775807}f", "9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"{} f func [ ,9223372036854775807 = m let ,1 += m ,m return ]\n,{}f", "9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{",(9223372036854775807) = a let\n,1 += (1)a\n,(1)a", "9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"2 ** 64", "18446744073709551616", "ERROR: integer overflow: 2 ** 64"},
+		{"9223372036854775807 + 1 - 2", "9223372036854775806", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"9223372036854775807 - 1", "9223372036854775806", "9223372036854775806"},
+		{"-3037000499 * 3037000499", "-9223372030926249001", "-9223372030926249001"},
 	}
+
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.unchecked {
+				t.Errorf("unchecked %q: expected=%q, got=%q", tt.input, tt.unchecked, got)
+			}
+		}
+	})
+
+	forEachCheckedEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.checked {
+				t.Errorf("checked %q: expected=%q, got=%q", tt.input, tt.checked, got)
+			}
+		}
+	})
 }
 
-func TestHashIndexExpressions(t *testing.T) {
+func TestBigIntAndDecimal(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected interface{}
+		expected string
 	}{
-		{"(1)[1= 2; 2= 3]", 2},
-		{"(2)[1= 2; 2= 3]", 3},
-		{"(1 + 1)[1= 2; 2= 3]", 3},
-		{",[1= 2; 2= 3] = myHash let\n(1)myHash", 2},
-		{",[1= 2; 2= 3] = myHash let\n(2)myHash", 3},
-		{",[1= 2; 2= 3] = myHash let\n(3)myHash", nil},
+		{"9223372036854775808", "9223372036854775808"},
+		{"9223372036854775808 + 1", "9223372036854775809"},
+		{"9223372036854775808 - 1", "9223372036854775807"},
+		{"-9223372036854775809", "-9223372036854775809"},
+		{"99999999999999999999 * 99999999999999999999", "9999999999999999999800000000000000000001"},
+		{"99999999999999999999 / 3", "33333333333333333333"},
+		{"99999999999999999999 > 1", "true"},
+		{"0xFFFF_FFFF_FFFF_FFFF + 0b1", "18446744073709551616"},
+		{"1_000.50d + 0x10", "1016.50"},
+		{"9223372036854775808 == 9223372036854775808", "true"},
+		{"9223372036854775808 + 0.5", "9.223372036854776e+18"},
+		{"{$77777777777777777777777$}bigint", "77777777777777777777777"},
+		{"{5}bigint", "5"},
+		{"{$abc$}bigint", "ERROR: could not parse abc as bigint"},
+		{"{$1.2.3$}decimal", "ERROR: could not parse 1.2.3 as decimal"},
+		{"0.1d + 0.2d == 0.3d", "true"},
+		{"0.1 + 0.2 == 0.3", "false"},
+		{"1.50d", "1.50"},
+		{"1.50d * 2", "3.00"},
+		{"1d / 3d", "0.3333333333333333"},
+		{"10.5d % 3", "1.5"},
+		{"-1.5d", "-1.5"},
+		{"1.5d < 2", "true"},
+		{"9223372036854775808 + 0.5d", "9223372036854775808.5"},
+		{"{1.5}decimal", "1.5"},
+		{"{$2.25$}decimal + 1", "3.25"},
+		{"$total: $ + 2.50d", "total: 2.50"},
+		{"1.5d + 1.5", "ERROR: type mismatch: DECIMAL + FLOAT"},
+		{"1d / 0", "ERROR: division by zero"},
+		{"9223372036854775808 % 0", "ERROR: division by zero"},
+		{",[9223372036854775808 = $big$; 5 = $small$] = h let\n,(9223372036854775808)h + ({5}bigint)h", "bigsmall"},
+		{",[1.5d = $x$] = h let\n,(1.50d)h", "x"},
+		{",1 = x let\n,0.5d += x\nx", "1.5"},
 	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
 
-		switch expected := tt.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(expected))
-		default:
-			testNullObject(t, evaluated)
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
 		}
-	}
+	})
 }
 
-func TestFuncCallAssignment(t *testing.T) {
-	input := `{a; b} add func [
-    ,a + b return
-]
+func TestNumericPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2.5", "3.5"},
+		{"2.5 + 1", "3.5"},
+		{"5 - 0.5", "4.5"},
+		{"3 * 0.5", "1.5"},
+		{"1 / 4.0", "0.25"},
+		{"1 < 1.5", "true"},
+		{"2.0 == 2", "true"},
+		{"2 != 2.5", "true"},
+		{"9223372036854775808 * 0.5", "4.611686018427388e+18"},
+		{"1 + 9223372036854775808", "9223372036854775809"},
+		{"1 + 0.5d", "1.5"},
+		{"0.5d + 0.5", "ERROR: type mismatch: DECIMAL + FLOAT"},
+		{"$n = $ + 1.5", "n = 1.500000"},
+		{",1 = x let\n,0.5 += x\nx", "1.5"},
+		{",1.5 = x let\n,2 *= x\nx", "3"},
+		{",10 = x let\n,4.0 /= x\nx", "2.5"},
+		{",1 = x let\n,$a$ += x", "ERROR: type mismatch: INTEGER += STRING"},
+		{",(1; 2) = xs let\n,0.5 += (1)xs\n(1)xs", "1.5"},
+	}
 
-,{2; 4}add = res let
-res`
-	evaluated := utils.EvalTest(input)
-	testIntegerObject(t, evaluated, 6)
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
 }
 
-func TestReassignmentOperators(t *testing.T) {
+func TestPowerAndIntegerDivision(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected int64
+		expected string
 	}{
-		{",5 = a let\n,5 += a\na", 10},
-		{",5 = a let\n,5 -= a\na", 0},
-		{",5 = a let\n,5 *= a\na", 25},
-		{",5 = a let\n,5 /= a\na", 1},
+		{"2 ** 10", "1024"},
+		{"2 ** 3 ** 2", "512"},
+		{"-2 ** 2", "-4"},
+		{"{-2} ** 3", "-8"},
+		{"2 ** 0", "1"},
+		{"2 ** -1", "0.5"},
+		{"0 ** -1", "ERROR: division by zero"},
+		{"2.0 ** 0.5", "1.4142135623730951"},
+		{"4 ** 0.5", "2"},
+		{"2 ** 64", "18446744073709551616"},
+		{"9223372036854775808 ** 2", "85070591730234615865843651857942052864"},
+		{"1.5d ** 2", "2.25"},
+		{"2d ** -2", "0.25"},
+		{"2d ** 0.5d", "ERROR: exponent of a DECIMAL must be a whole number, got 0.5"},
+		{"7 // 2", "3"},
+		{"-7 // 2", "-4"},
+		{"-7 / 2", "-3"},
+		{"7 // -2", "-4"},
+		{"7.5 // 2", "3"},
+		{"-7.5 // 2", "-4"},
+		{"-9223372036854775809 // 2", "-4611686018427387905"},
+		{"-7.5d // 2", "-4"},
+		{"7 // 0", "ERROR: division by zero"},
+		{"7.5 // 0", "ERROR: division by zero"},
+		{"7.5 % 2", "1.5"},
+		{"-7.5 % 2", "-1.5"},
+		{"7 % 2.5", "2"},
+		{"7.5 % 0.0", "ERROR: division by zero"},
 	}
-	for _, tt := range tests {
-		evaluated := utils.EvalTest(tt.input)
-		testIntegerObject(t, evaluated, tt.expected)
+
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
+
+	forEachCheckedEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		if got := eval("2 ** 64").Inspect(); got != "ERROR: integer overflow: 2 ** 64" {
+			t.Errorf("checked 2 ** 64: got=%q", got)
+		}
+		if got := eval("2 ** 62").Inspect(); got != "4611686018427387904" {
+			t.Errorf("checked 2 ** 62: got=%q", got)
+		}
+	})
+}
+
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"true && false", "false"},
+		{"true || false", "true"},
+		{"false && true", "false"},
+		{"false || false", "false"},
+		{"1 && 2", "2"},
+		{"0 || 2", "0"},
+		{"false || $default$", "default"},
+		{"$set$ || $default$", "set"},
+		{"(1)[2 = 3] && 5", "null"},
+		{"false && {$boom$}raise", "false"},
+		{"true || {$boom$}raise", "true"},
+		{"true && {$boom$}raise", "ERROR: boom"},
+		{",(1; 2) = arr let\n,3 = i let\n,{i <= {arr}len && (i)arr > 0} if [ ,$in$ ] else [ ,$out$ ]", "out"},
+		{",[$a$ = 1] = h let\n,($a$)h ?? 10", "1"},
+		{",[$a$ = 1] = h let\n,($b$)h ?? 10", "10"},
+		{",[$a$ = false] = h let\n,($a$)h ?? true", "false"},
+		{",[] = h let\n,($a$)h ?? ($b$)h ?? 3", "3"},
+		{"5 ?? {$boom$}raise", "5"},
+		{",[] = config let\n,($port$)config ?? 8080 = port let\n,port", "8080"},
+		{",false || $stranger$ = name let\n,name", "stranger"},
+		{",1 = x let\n,false || 2 += x\nx", "3"},
+		{",0 = calls let\n{} bump func [ ,1 += calls\n,true return ]\n,true || {}bump\n,false && {}bump\n,calls", "0"},
 	}
+
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
 }
 
-func TestWhileExpression(t *testing.T) {
-	input := `,0 = x let
+// TestUnicodeStrings checks that strings count code points, so an emoji is
+// one character and an accent written as a combining mark is one more
+func TestUnicodeStrings(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`{$héllo$}len`, "5"},
+			{`{$😀$}len`, "1"},
+			{`{$👍🏽$}len`, "2"},
+			{`{$e\u{301}$}len`, "2"},
+			{`(2)$héllo$`, "é"},
+			{`(2)$a😀b$`, "😀"},
+			{`(2)$e\u{301}$ == $\u{301}$`, "true"},
+			{`{$a😀b$}chars`, "(a; 😀; b)"},
+			{`{$😀héllo$; 2; 3}substr`, "hél"},
+			{`{$😀héllo$; $l$}indexOf`, "4"},
+			{`{$héllo$}upper`, "HÉLLO"},
+			{`,1 = zähler let ,2 += zähler ,zähler`, "3"},
+			{`{x} grüß func [ ,$hallo $ + x return ] ,{$wörld$}grüß`, "hallo wörld"},
+		}
+		for _, tt := range tests {
+			evaluated := eval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
+	})
+}
 
-{x < 5} while [
-	,1 += x
-]
+func TestRegisterBuiltin(t *testing.T) {
+	evaluator.RegisterBuiltin("hostTwice", func(ctx *object.CallContext, args ...object.Object) object.Object {
+		return ctx.Call(args[0], ctx.Call(args[0], args[1]))
+	})
 
-x
-`
-	evaluated := utils.EvalTest(input)
-	testIntegerObject(t, evaluated, 5)
+	forEachEngine(t, func(t *testing.T, eval func(string) object.Object) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`,{{x} func [ ,x * 2 return ]; 3}hostTwice`, "12"},
+			// variables of the program still win over builtins
+			{`,1 = hostTwice let ,hostTwice`, "1"},
+		}
+		for _, tt := range tests {
+			if got := eval(tt.input).Inspect(); got != tt.expected {
+				t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+		}
+	})
 }
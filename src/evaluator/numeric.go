@@ -0,0 +1,251 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+// The numeric tower. When two numbers meet, the one with the lower rank is
+// promoted to the type of the other. Decimals are exact and floats are not,
+// so those two never mix, a decimal has to be converted explicitly.
+const (
+	integerRank = iota + 1
+	bigIntRank
+	decimalRank
+	floatRank
+)
+
+// isNumericOperator reports whether operator applies to numbers, it runs on
+// every operation so it is a switch rather than a map
+func isNumericOperator(operator string) bool {
+	switch operator {
+	case "+", "-", "*", "/", "%", "**", "//", "<", ">", "<=", ">=", "==", "!=":
+		return true
+	}
+	return false
+}
+
+// numericRank places obj in the numeric tower, it is 0 for anything that is
+// not a number
+func numericRank(obj object.Object) int {
+	switch obj.(type) {
+	case *object.Integer:
+		return integerRank
+	case *object.BigInt:
+		return bigIntRank
+	case *object.Decimal:
+		return decimalRank
+	case *object.Float:
+		return floatRank
+	default:
+		return 0
+	}
+}
+
+// promotedRank is the rank both operands are promoted to, or 0 when they
+// can't be mixed
+func promotedRank(left, right object.Object) int {
+	l, r := numericRank(left), numericRank(right)
+	if l == 0 || r == 0 {
+		return 0
+	}
+	if max(l, r) == floatRank && min(l, r) == decimalRank {
+		return 0
+	}
+	return max(l, r)
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	default:
+		return obj.(*object.BigInt).Value
+	}
+}
+
+func toDecimal(obj object.Object) *object.Decimal {
+	switch obj := obj.(type) {
+	case *object.Decimal:
+		return obj
+	default:
+		return object.NewDecimalFromInt(toBigInt(obj))
+	}
+}
+
+// toFloat is used when an integer meets a float, the result is as inexact as
+// any other float
+func toFloat(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &object.Float{Value: float64(obj.Value)}
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return &object.Float{Value: value}
+	default:
+		return obj
+	}
+}
+
+// evalNumericInfixExpression promotes both operands to the same type before
+// applying operator
+func evalNumericInfixExpression(operator string, rank int, left, right object.Object, checked bool) object.Object {
+	if !isNumericOperator(operator) {
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	switch rank {
+	case integerRank:
+		return evalIntegerInfixExpression(operator, left, right, checked)
+	case bigIntRank:
+		return evalBigIntInfixExpression(operator, toBigInt(left), toBigInt(right))
+	case decimalRank:
+		return evalDecimalInfixExpression(operator, toDecimal(left), toDecimal(right))
+	default:
+		return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
+	}
+}
+
+func evalBigIntInfixExpression(operator string, left, right *big.Int) object.Object {
+	result := new(big.Int)
+
+	switch operator {
+	case "+":
+		return &object.BigInt{Value: result.Add(left, right)}
+	case "-":
+		return &object.BigInt{Value: result.Sub(left, right)}
+	case "*":
+		return &object.BigInt{Value: result.Mul(left, right)}
+	case "/", "%", "//":
+		if right.Sign() == 0 {
+			return newZeroDivisionError("division by zero")
+		}
+
+		quotient, remainder := result.QuoRem(left, right, new(big.Int))
+		switch operator {
+		case "/":
+			return &object.BigInt{Value: quotient}
+		case "%":
+			return &object.BigInt{Value: remainder}
+		}
+
+		if remainder.Sign() != 0 && (remainder.Sign() < 0) != (right.Sign() < 0) {
+			quotient.Sub(quotient, big.NewInt(1))
+		}
+		return &object.BigInt{Value: quotient}
+	case "**":
+		if right.Sign() < 0 {
+			return evalFloatInfixExpression(operator, toFloat(&object.BigInt{Value: left}), toFloat(&object.BigInt{Value: right}))
+		}
+		if !right.IsInt64() {
+			return newOverflowError("exponent too large: %s", right)
+		}
+		return &object.BigInt{Value: result.Exp(left, right, nil)}
+	default:
+		return evalComparison(operator, left.Cmp(right), object.BIGINT_OBJ)
+	}
+}
+
+func evalDecimalInfixExpression(operator string, left, right *object.Decimal) object.Object {
+	switch operator {
+	case "+":
+		return left.Add(right)
+	case "-":
+		return left.Sub(right)
+	case "*":
+		return left.Mul(right)
+	case "/", "%", "//":
+		if right.IsZero() {
+			return newZeroDivisionError("division by zero")
+		}
+		switch operator {
+		case "/":
+			return left.Quo(right)
+		case "%":
+			return left.Rem(right)
+		default:
+			return left.QuoFloor(right)
+		}
+	case "**":
+		exponent, ok := right.Int()
+		if !ok {
+			return newValueError("exponent of a DECIMAL must be a whole number, got %s", right.Inspect())
+		}
+		if !exponent.IsInt64() {
+			return newOverflowError("exponent too large: %s", exponent)
+		}
+		if exponent.Sign() < 0 && left.IsZero() {
+			return newZeroDivisionError("division by zero")
+		}
+		return left.Pow(exponent.Int64())
+	default:
+		return evalComparison(operator, left.Cmp(right), object.DECIMAL_OBJ)
+	}
+}
+
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Float).Value
+	rightVal := right.(*object.Float).Value
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/", "%", "//":
+		if rightVal == 0 {
+			return newZeroDivisionError("division by zero")
+		}
+		switch operator {
+		case "/":
+			return &object.Float{Value: leftVal / rightVal}
+		case "%":
+			return &object.Float{Value: math.Mod(leftVal, rightVal)}
+		default:
+			return &object.Float{Value: math.Floor(leftVal / rightVal)}
+		}
+	case "**":
+		if leftVal == 0 && rightVal < 0 {
+			return newZeroDivisionError("division by zero")
+		}
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	default:
+		return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalComparison turns the result of a three way comparison into a boolean
+func evalComparison(operator string, cmp int, t object.ObjectTypes) object.Object {
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(cmp < 0)
+	case ">":
+		return nativeBoolToBooleanObject(cmp > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(cmp <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(cmp >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(cmp == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(cmp != 0)
+	default:
+		return newTypeError("unknown operator: %s %s %s", t, operator, t)
+	}
+}
@@ -0,0 +1,75 @@
+package evaluator
+
+import "github.com/SirusCodes/anti-lang/src/object"
+
+// The helpers below expose the tree walker's semantics to the other engines
+// (see the vm package) so both agree on every operator and error message.
+
+// EvalPrefix applies operator to right, checked makes integer overflow an
+// error like Environment.CheckedArithmetic does for the tree walker
+func EvalPrefix(operator string, right object.Object, checked bool) object.Object {
+	return evalPrefixExpression(operator, right, checked)
+}
+
+func EvalInfix(operator string, left, right object.Object, checked bool) object.Object {
+	return evalInfixExpression(operator, left, right, checked)
+}
+
+func EvalIndex(array, index object.Object) object.Object {
+	return evalIndexExpression(array, index)
+}
+
+// AssignOperator returns the value a variable holding current has once value
+// has been assigned to it with operator
+func AssignOperator(operator string, current, value object.Object, checked bool) object.Object {
+	return evalAssignOperator(operator, current, value, checked)
+}
+
+func EvalIndexAssign(container, index object.Object, operator string, value object.Object, checked bool) object.Object {
+	return evalIndexAssignExpression(container, index, operator, value, checked)
+}
+
+// Import loads the module at path for the program running in env
+func Import(path string, env *object.Environment) object.Object {
+	return importModule(path, env)
+}
+
+func EvalMember(obj object.Object, name string) object.Object {
+	return evalMemberExpression(obj, name)
+}
+
+// Call applies fn to args as if it was called from env
+func Call(fn object.Object, args []object.Object, env *object.Environment) object.Object {
+	return applyFunction(fn, args, env)
+}
+
+// NewIterator creates the iterator of a for loop from its iterable or range bounds
+func NewIterator(args ...object.Object) object.Object {
+	return newIterator(args)
+}
+
+// LookupBuiltin returns the builtin function registered under name, for the
+// program env belongs to or for every program
+func LookupBuiltin(name string, env *object.Environment) (*object.Builtin, bool) {
+	return lookupBuiltin(name, env)
+}
+
+// ShortCircuits reports whether the left operand of && || or ?? is the result
+// on its own
+func ShortCircuits(operator string, left object.Object) bool {
+	return shortCircuits(operator, left)
+}
+
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+func NewError(format string, a ...interface{}) *object.Error {
+	return newError(format, a...)
+}
+
+// NewKindError is NewError for an error of kind, one of TypeError, NameError
+// and the other kinds the evaluator raises
+func NewKindError(kind, format string, a ...interface{}) *object.Error {
+	return newKindError(kind, format, a...)
+}
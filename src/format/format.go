@@ -0,0 +1,466 @@
+// Package format pretty-prints AntiLang source in its canonical layout
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/parser"
+)
+
+// Indent is written once per level of nesting
+const Indent = "    "
+
+// atom is the precedence of anything that is not an operator, it never needs
+// braces around it
+const atom = parser.MEMBER + 1
+
+// Source formats src, which has to parse without errors. Comments are kept
+// and formatting the result again gives the same result.
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	var comments []lexer.Token
+	stringEnds := map[int]int{}
+	l := lexer.NewWithComments(string(src))
+	for tok := l.NextToken(); tok.Type != lexer.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case lexer.COMMENT:
+			comments = append(comments, tok)
+		case lexer.STRING:
+			// escapes are decoded in the value, only the source tells the
+			// lines a string spans
+			stringEnds[tok.Pos.Offset] = l.Position().Line
+		}
+	}
+
+	return printProgram(program, comments, stringEnds), nil
+}
+
+// Program prints a parsed program along with the comments of its source.
+// Blank lines and the place of every comment are taken from the positions
+// of the tokens, a string spanning lines is taken to end on its first one.
+func Program(program *ast.Program, comments []lexer.Token) []byte {
+	return printProgram(program, comments, nil)
+}
+
+func printProgram(program *ast.Program, comments []lexer.Token, stringEnds map[int]int) []byte {
+	pr := &printer{comments: comments, stringEnds: stringEnds, atStart: true}
+	pr.statements(program.Statements, math.MaxInt)
+	pr.commentsBefore(math.MaxInt)
+
+	if pr.out.Len() == 0 {
+		return nil
+	}
+	pr.out.WriteByte('\n')
+
+	return pr.out.Bytes()
+}
+
+type printer struct {
+	out   bytes.Buffer
+	depth int
+
+	comments   []lexer.Token // the comments that have not been printed yet
+	stringEnds map[int]int   // the line every string ends on, by offset
+
+	atStart  bool // nothing has been printed in the current block yet
+	lastLine int  // the source line the last statement or comment ended on
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+	p.write(strings.Repeat(Indent, p.depth))
+}
+
+// line starts the line of something that began on line in the source,
+// keeping a single blank line where the source had one or more
+func (p *printer) line(line int) {
+	if !p.atStart {
+		if line > p.lastLine+1 {
+			p.out.WriteByte('\n')
+		}
+		p.newline()
+	}
+	p.atStart = false
+}
+
+// statements puts every statement on a line of its own. Comments in front of
+// a statement stay on lines of their own, the ones on its last line up to
+// the end offset of the block follow it.
+func (p *printer) statements(stmts []ast.Statement, end int) {
+	for _, stmt := range stmts {
+		first, last, offset := p.span(stmt)
+
+		p.commentsBefore(offset)
+		p.line(first)
+		p.statement(stmt)
+		p.trailingComments(last, end)
+		p.lastLine = max(p.lastLine, last)
+	}
+}
+
+// commentsBefore prints the comments before offset on lines of their own
+func (p *printer) commentsBefore(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.line(comment.Pos.Line)
+		p.write(comment.Literal)
+		p.lastLine = comment.Pos.Line + strings.Count(comment.Literal, "\n")
+	}
+}
+
+// trailingComments prints the comments up to line and before offset end
+// after what is already on the current line. A line comment ends the line,
+// anything after it goes on a line of its own.
+func (p *printer) trailingComments(line, end int) {
+	lineComment := false
+
+	for len(p.comments) > 0 && p.comments[0].Pos.Line <= line && p.comments[0].Pos.Offset < end {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if lineComment {
+			p.newline()
+		} else {
+			p.write(" ")
+		}
+		p.write(comment.Literal)
+
+		lineComment = strings.HasPrefix(comment.Literal, "\\\\")
+		p.lastLine = max(p.lastLine, comment.Pos.Line+strings.Count(comment.Literal, "\n"))
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStatement:
+		// definitions, conditionals and loops stand on their own, anything
+		// else is started with a ',' so it can't run into the previous line
+		if isCompound(s.Expression) {
+			p.compound(s.Expression, false)
+			return
+		}
+		p.write(",")
+		p.expression(s.Expression)
+	case *ast.LetStatement:
+		p.write(",")
+		p.operand(s.Value, parser.ASSIGN+1, false)
+		p.write(" = " + s.Name.Value + " let")
+	case *ast.ReturnStatement:
+		p.write(",")
+		p.operand(s.ReturnValue, parser.ASSIGN+1, false)
+		p.write(" return")
+	case *ast.ImportStatement:
+		p.write(",")
+		p.expression(s.Path)
+		if s.Name != nil {
+			p.write(" = " + s.Name.Value)
+		}
+		p.write(" import")
+	case *ast.BreakStatement:
+		p.write(",break")
+	case *ast.ContinueStatement:
+		p.write(",continue")
+	}
+}
+
+// block prints the statements of b one per line. A short block inside an
+// expression, like the body of a lambda passed to map, stays on one line.
+func (p *printer) block(b *ast.BlockStatement, inline bool) {
+	if len(b.Statements) == 0 {
+		p.write("[]")
+		return
+	}
+
+	if inline && len(b.Statements) == 1 && !hasBlock(b.Statements[0]) && !p.hasComments(b) {
+		p.write("[ ")
+		p.statement(b.Statements[0])
+		p.write(" ]")
+		return
+	}
+
+	p.write("[")
+	p.depth++
+	p.newline()
+	p.atStart = true
+	p.statements(b.Statements, b.Close.Pos.Offset)
+	p.commentsBefore(b.Close.Pos.Offset)
+	p.depth--
+	p.newline()
+	p.write("]")
+}
+
+// hasComments reports whether there are comments between the brackets of b
+func (p *printer) hasComments(b *ast.BlockStatement) bool {
+	for _, comment := range p.comments {
+		if comment.Pos.Offset > b.Token.Pos.Offset && comment.Pos.Offset < b.Close.Pos.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *printer) expression(exp ast.Expression) {
+	switch e := exp.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.BigIntLiteral, *ast.DecimalLiteral, *ast.BooleanLiteral:
+		p.write(e.TokenLiteral())
+	case *ast.StringLiteral:
+		if e.Token.Type == lexer.RAW_STRING {
+			p.write("$$$" + e.Value + "$$$")
+		} else {
+			p.write(quote(e.Value))
+		}
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		p.operand(e.Right, parser.PREFIX, true)
+	case *ast.InfixExpression:
+		precedence := parser.Precedence(e.Token.Type)
+		left, right := precedence, precedence+1
+		// ** groups to the right
+		if e.Token.Type == lexer.POWER {
+			left, right = precedence+1, precedence
+		}
+
+		p.operand(e.Left, left, false)
+		p.write(" " + e.Operator + " ")
+		p.operand(e.Right, right, true)
+	case *ast.AssignExpression:
+		p.operand(e.Value, parser.ASSIGN+1, false)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Target)
+	case *ast.CallExpression:
+		p.list("{", e.Arguments, "}")
+		p.expression(e.Function)
+	case *ast.MemberExpression:
+		p.operand(e.Object, parser.MEMBER, false)
+		p.write("." + e.Property.Value)
+	case *ast.IndexExpression:
+		p.write("(")
+		p.expression(e.Index)
+		p.write(")")
+		p.expression(e.Array)
+	case *ast.ArrayLiteral:
+		p.list("(", e.Elements, ")")
+	case *ast.HashLiteral:
+		p.write("[")
+		for i, key := range e.OrderedKeys() {
+			if i > 0 {
+				p.write("; ")
+			}
+			p.expression(key)
+			p.write(" = ")
+			p.expression(e.Pairs[key])
+		}
+		p.write("]")
+	default:
+		p.compound(exp, true)
+	}
+}
+
+// operand prints exp wrapped in braces when it binds less tightly than min.
+// A prefix operator on the right of another operator never needs them, it
+// only takes the operand right after it.
+func (p *printer) operand(exp ast.Expression, min int, right bool) {
+	if _, ok := exp.(*ast.PrefixExpression); ok && right {
+		p.expression(exp)
+		return
+	}
+
+	if precedence(exp) < min {
+		p.write("{")
+		p.expression(exp)
+		p.write("}")
+		return
+	}
+
+	p.expression(exp)
+}
+
+func (p *printer) list(open string, exps []ast.Expression, close string) {
+	p.write(open)
+	for i, exp := range exps {
+		if i > 0 {
+			p.write("; ")
+		}
+		p.expression(exp)
+	}
+	p.write(close)
+}
+
+func (p *printer) parameters(params []*ast.Identifier) {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Value
+	}
+	p.write("{" + strings.Join(names, "; ") + "}")
+}
+
+// compound prints the expressions that end in a block
+func (p *printer) compound(exp ast.Expression, inline bool) {
+	switch e := exp.(type) {
+	case *ast.FunctionExpression:
+		p.parameters(e.Parameters)
+		p.write(" " + e.Token.Literal + " func ")
+		p.block(e.Body, inline)
+	case *ast.FunctionLiteral:
+		p.parameters(e.Parameters)
+		p.write(" func ")
+		p.block(e.Body, inline)
+	case *ast.ConditionalExpression:
+		p.write("{")
+		p.expression(e.Condition)
+		p.write("} if ")
+		p.block(e.ExecutionBlock, inline)
+
+		for next := e.NextConditional; next != nil; next = next.NextConditional {
+			if next.Condition == nil {
+				p.write(" else ")
+			} else {
+				p.write(" {")
+				p.expression(next.Condition)
+				p.write("} if else ")
+			}
+			p.block(next.ExecutionBlock, inline)
+		}
+	case *ast.WhileExpression:
+		p.write("{")
+		p.expression(e.Condition)
+		p.write("} while ")
+		p.block(e.Body, inline)
+	case *ast.ForExpression:
+		args := []ast.Expression{e.Variable, e.Iterable}
+		if e.Iterable == nil {
+			args = []ast.Expression{e.Variable, e.Start, e.End}
+			if e.Step != nil {
+				args = append(args, e.Step)
+			}
+		}
+		p.list("{", args, "}")
+		p.write(" for ")
+		p.block(e.Body, inline)
+	case *ast.TryExpression:
+		p.write("try ")
+		p.block(e.Body, inline)
+		p.write(" {")
+		if e.Variable != nil {
+			p.write(e.Variable.Value)
+		}
+		p.write("} catch ")
+		p.block(e.Handler, inline)
+	}
+}
+
+func isCompound(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.FunctionExpression, *ast.FunctionLiteral, *ast.ConditionalExpression,
+		*ast.WhileExpression, *ast.ForExpression, *ast.TryExpression:
+		return true
+	default:
+		return false
+	}
+}
+
+// precedence is how tightly exp holds together when it is the operand of an
+// operator
+func precedence(exp ast.Expression) int {
+	switch e := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.AssignExpression:
+		return parser.ASSIGN
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	default:
+		return atom
+	}
+}
+
+func hasBlock(stmt ast.Statement) bool {
+	found := false
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		_, ok := n.(*ast.BlockStatement)
+		found = found || ok
+		return !found
+	})
+	return found
+}
+
+// span returns the first and last line of node in the source it was parsed
+// from, and the offset it starts at
+func (p *printer) span(node ast.Node) (first, last, offset int) {
+	offset = math.MaxInt
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		pos := n.Pos()
+		end := pos.Line
+
+		switch n := n.(type) {
+		case *ast.BlockStatement:
+			end = n.Close.Pos.Line
+		case *ast.StringLiteral:
+			if n.Token.Type == lexer.RAW_STRING {
+				end = pos.Line + strings.Count(n.Value, "\n")
+			} else if line, ok := p.stringEnds[pos.Offset]; ok {
+				end = line
+			}
+		}
+
+		if pos.IsValid() && (first == 0 || pos.Line < first) {
+			first = pos.Line
+		}
+		if pos.IsValid() {
+			offset = min(offset, pos.Offset)
+		}
+		last = max(last, end)
+		return true
+	})
+	return first, last, offset
+}
+
+// quote writes s as a string literal, escaping whatever can't be written
+// between two $ as it is
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('$')
+
+	for _, r := range s {
+		switch r {
+		case '$', '\\':
+			out.WriteRune('\\')
+			out.WriteRune(r)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&out, `\u{%X}`, r)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+
+	out.WriteByte('$')
+	return out.String()
+}
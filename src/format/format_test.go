@@ -0,0 +1,215 @@
+package format_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/src/format"
+	"github.com/SirusCodes/anti-lang/src/utils"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{",1+2", ",1 + 2\n"},
+		{",5=x let", ",5 = x let\n"},
+		{",x*2 return", ",x * 2 return\n"},
+		{",$utils.al$=u import", ",$utils.al$ = u import\n"},
+		{",1 += i ,2", ",1 += i\n,2\n"},
+		{",(1;2;3)=arr let", ",(1; 2; 3) = arr let\n"},
+		{",[$a$=1;2=(1)arr]", ",[$a$ = 1; 2 = (1)arr]\n"},
+		{",{1;{2}double}add", ",{1; {2}double}add\n"},
+		{",{1}utils.add", ",{1}utils.add\n"},
+		{",{1+2}*3", ",{1 + 2} * 3\n"},
+		{",1+{2*3}", ",1 + 2 * 3\n"},
+		{",1-{2-3}", ",1 - {2 - 3}\n"},
+		{",{1-2}-3", ",1 - 2 - 3\n"},
+		{",2**{3**2}", ",2 ** 3 ** 2\n"},
+		{",{2**3}**2", ",{2 ** 3} ** 2\n"},
+		{",{-2}**2", ",{-2} ** 2\n"},
+		{",-{2**2}", ",-2 ** 2\n"},
+		{",-{a+b}", ",-{a + b}\n"},
+		{",a*-b", ",a * -b\n"},
+		{",{1 += x}+2", ",{1 += x} + 2\n"},
+		{",a||b = c let", ",a || b = c let\n"},
+		{"{a;b} add func [,a+b return]", "{a; b} add func [\n    ,a + b return\n]\n"},
+		{"{} noop func [ ]", "{} noop func []\n"},
+		{
+			",{{3}range;{x} func [,x*x return]}map",
+			",{{3}range; {x} func [ ,x * x return ]}map\n",
+		},
+		{
+			",{xs;{x} func [,{x} if [,1 return] ,0 return]}map",
+			",{xs; {x} func [\n    {x} if [\n        ,1 return\n    ]\n    ,0 return\n]}map\n",
+		},
+		{
+			"{x} if [,1] {y} if else [,2] else [,3]",
+			"{x} if [\n    ,1\n] {y} if else [\n    ,2\n] else [\n    ,3\n]\n",
+		},
+		{
+			",{x} if [,1] else [,2] = y let",
+			",{x} if [ ,1 ] else [ ,2 ] = y let\n",
+		},
+		{
+			"{i; 1; 10; 2} for [,{i}print] {x; xs} for [,continue]",
+			"{i; 1; 10; 2} for [\n    ,{i}print\n]\n{x; xs} for [\n    ,continue\n]\n",
+		},
+		{
+			"{i < 3} while [,1 += i]",
+			"{i < 3} while [\n    ,1 += i\n]\n",
+		},
+		{
+			"try [,{$x$}raise] {e} catch [,e.message] try [,1] {} catch [,2]",
+			"try [\n    ,{$x$}raise\n] {e} catch [\n    ,e.message\n]\ntry [\n    ,1\n] {} catch [\n    ,2\n]\n",
+		},
+		{
+			// blank lines are kept, but only one of them
+			",1 = a let\n\n\n\n,2 = b let\n,3 = c let\n",
+			",1 = a let\n\n,2 = b let\n,3 = c let\n",
+		},
+		{
+			"{x} f func [\n\n,1\n\n,2\n\n]\n,3",
+			"{x} f func [\n    ,1\n\n    ,2\n]\n,3\n",
+		},
+		{
+			"{x} if [\n,1\n]\n,2",
+			"{x} if [\n    ,1\n]\n,2\n",
+		},
+		{",0xFF+1_000*6.02e23", ",0xFF + 1_000 * 6.02e23\n"},
+		// strings are escaped again, raw strings are kept as they are
+		{`,$a \$5 \\ \u{41}\u{7}$`, ",$a \\$5 \\\\ A\\u{7}$\n"},
+		{`,{$tab\tnew\n$}print`, ",{$tab\\tnew\\n$}print\n"},
+		{
+			"\\\\ two lines\n,$$$C:\\$\n\\n$$$ = a let \\\\ raw\n\n,a",
+			"\\\\ two lines\n,$$$C:\\$\n\\n$$$ = a let \\\\ raw\n\n,a\n",
+		},
+		// a string spanning lines keeps the comments and blank lines after it
+		{",$a\nb\nc$ \\\\ note\n,x\n\n,y", ",$a\\nb\\nc$ \\\\ note\n,x\n\n,y\n"},
+		// comments stay where they were
+		{"\\\\ only a comment", "\\\\ only a comment\n"},
+		{
+			"\\\\ header\n\n,1=a let \\\\ one\n*/ two\n   lines /*\n,2=b let\n\n\\\\ end",
+			"\\\\ header\n\n,1 = a let \\\\ one\n*/ two\n   lines /*\n,2 = b let\n\n\\\\ end\n",
+		},
+		{
+			",1 */ a /* */ b /* \\\\ c\n,2",
+			",1 */ a /* */ b /* \\\\ c\n,2\n",
+		},
+		{
+			"{x} f func [\\\\ first\n,x return \\\\ x\n\\\\ last\n]",
+			"{x} f func [\n    \\\\ first\n    ,x return \\\\ x\n    \\\\ last\n]\n",
+		},
+		{
+			// a block with comments is never put on one line
+			",{xs;{x} func [,x \\\\ same\n]}map",
+			",{xs; {x} func [\n    ,x \\\\ same\n]}map\n",
+		},
+		{
+			"{a} if [,1] else [*/ e /* ,2] \\\\ after",
+			"{a} if [\n    ,1\n] else [\n    */ e /*\n    ,2\n] \\\\ after\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := format.Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tt.input, err)
+			continue
+		}
+
+		if string(got) != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+
+		again, err := format.Source(got)
+		if err != nil || string(again) != string(got) {
+			t.Errorf("%q: formatting is not idempotent, got=%q (%v)", tt.input, again, err)
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	_, err := format.Source([]byte(",1 = let"))
+	if err == nil || !strings.HasPrefix(err.Error(), "1:6:") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+// TestSamples formats every sample and checks that the result means the same
+// program and stays put when formatted again
+func TestSamples(t *testing.T) {
+	paths, err := filepath.Glob("../../sample/*.al")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no samples found: %v", err)
+	}
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := format.Source(src)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+
+		original := utils.ParseInput(t, string(src))
+		reparsed := utils.ParseInput(t, string(formatted))
+		if len(original.Statements) != len(reparsed.Statements) || original.String() != reparsed.String() {
+			t.Errorf("%s: formatting changed the program\n%s", path, formatted)
+		}
+
+		again, _ := format.Source(formatted)
+		if string(again) != string(formatted) {
+			t.Errorf("%s: formatting is not idempotent\n%s\n%s", path, formatted, again)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if diff := format.Diff("a.al", []byte(",1\n"), []byte(",1\n")); diff != nil {
+		t.Errorf("expected no diff, got %q", diff)
+	}
+
+	old := ",1\n,2\n,3\n,4\n,5\n,6\n,7\n,8\n,9\n,10\n,11\n,12\n"
+	new := ",1\n,two\n,3\n,4\n,5\n,6\n,7\n,8\n,9\n,10\n,11\n,12\n,13\n"
+
+	expected := `--- a.al.orig
++++ a.al
+@@ -1,5 +1,5 @@
+ ,1
+-,2
++,two
+ ,3
+ ,4
+ ,5
+@@ -10,3 +10,4 @@
+ ,10
+ ,11
+ ,12
++,13
+`
+
+	if diff := format.Diff("a.al", []byte(old), []byte(new)); string(diff) != expected {
+		t.Errorf("wrong diff, expected=\n%s\ngot=\n%s", expected, diff)
+	}
+
+	expected = `--- a.al.orig
++++ a.al
+@@ -1,1 +1,1 @@
+-,1
+\ No newline at end of file
++,1
+`
+
+	if diff := format.Diff("a.al", []byte(",1"), []byte(",1\n")); string(diff) != expected {
+		t.Errorf("wrong diff, expected=\n%s\ngot=\n%s", expected, diff)
+	}
+}
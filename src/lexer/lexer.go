@@ -1,29 +1,54 @@
 package lexer
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 type Lexer struct {
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
-}
+	position     int  // current byte offset in input (points to current char)
+	readPosition int  // current reading offset in input (after current char)
+	ch           rune // current char under examination, decoded from UTF-8
+	line         int  // line of the current char, starting at 1
+	column       int  // column of the current char in characters, starting at 1
 
-var (
-	tempPosition     int
-	tempReadPosition int
-	tempCh           byte
-)
+	comments bool // whether comments are returned as COMMENT tokens
+}
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// NewWithComments returns a lexer that hands out comments as COMMENT tokens
+// instead of skipping them, for tools that have to keep them around
+func NewWithComments(input string) *Lexer {
+	l := New(input)
+	l.comments = true
+	return l
+}
+
 func (l *Lexer) NextToken() Token {
+	for {
+		tok := l.nextToken()
+		if tok.Type != COMMENT || l.comments {
+			return tok
+		}
+	}
+}
+
+func (l *Lexer) nextToken() Token {
 	var tok Token
 
 	l.skipWhitespace()
 
+	pos := l.currentPosition()
+
 	switch l.ch {
 	case '=':
 		tok = l.makeTwoCharToken(EQ, ASSIGN)
@@ -34,9 +59,27 @@ func (l *Lexer) NextToken() Token {
 	case '!':
 		tok = l.makeTwoCharToken(NOT_EQ, BANG)
 	case '/':
-		tok = l.makeTwoCharToken(SLASH_EQ, SLASH)
+		if l.peekChar() == '/' {
+			l.readChar()
+			tok = Token{Type: INT_DIV, Literal: "//"}
+		} else {
+			tok = l.makeTwoCharToken(SLASH_EQ, SLASH)
+		}
+	case '\\':
+		if l.peekChar() == '\\' {
+			tok = l.readLineComment()
+		} else {
+			tok = newToken(ILLEGAL, l.ch)
+		}
 	case '*':
-		tok = l.makeTwoCharToken(ASTER_EQ, ASTERISK)
+		if l.peekChar() == '/' {
+			tok = l.readBlockComment()
+		} else if l.peekChar() == '*' {
+			l.readChar()
+			tok = Token{Type: POWER, Literal: "**"}
+		} else {
+			tok = l.makeTwoCharToken(ASTER_EQ, ASTERISK)
+		}
 	case '<':
 		tok = l.makeTwoCharToken(LT_EQ, LT)
 	case '>':
@@ -45,6 +88,8 @@ func (l *Lexer) NextToken() Token {
 		tok = newToken(SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(COMMA, l.ch)
+	case '.':
+		tok = newToken(DOT, l.ch)
 	case '{':
 		tok = newToken(LBRACE, l.ch)
 	case '}':
@@ -77,8 +122,18 @@ func (l *Lexer) NextToken() Token {
 		} else {
 			tok = newToken(ILLEGAL, l.ch)
 		}
+	case '?':
+		if l.peekChar() == '?' {
+			l.readChar()
+			tok = Token{Type: NULLISH, Literal: "??"}
+		} else {
+			tok = newToken(ILLEGAL, l.ch)
+		}
 	case '$':
-		tok = l.readString()
+		if strings.HasPrefix(l.input[l.position:], "$$$") {
+			return l.readRawString(pos)
+		}
+		return l.readString(pos)
 	case 0:
 		tok.Literal = ""
 		tok.Type = EOF
@@ -86,9 +141,11 @@ func (l *Lexer) NextToken() Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
 			tok = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(ILLEGAL, l.ch)
@@ -96,32 +153,20 @@ func (l *Lexer) NextToken() Token {
 	}
 
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
+// MoveReaderForTemp runs fn and then moves the lexer back to where it was,
+// so fn can read ahead. The state is kept on the stack, calls can nest and
+// lexers don't share anything.
 func (l *Lexer) MoveReaderForTemp(fn func()) {
-	l.saveTokenState()
-	defer l.restoreTokenState()
+	saved := *l
+	defer func() { *l = saved }()
 
 	fn()
 }
 
-func (l *Lexer) saveTokenState() {
-	tempPosition = l.position
-	tempReadPosition = l.readPosition
-	tempCh = l.ch
-}
-
-func (l *Lexer) restoreTokenState() {
-	l.position = tempPosition
-	l.readPosition = tempReadPosition
-	l.ch = tempCh
-
-	tempCh = 0
-	tempPosition = 0
-	tempReadPosition = 0
-}
-
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
@@ -129,26 +174,45 @@ func (l *Lexer) skipWhitespace() {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += max(width, 1)
+	l.column += 1
+}
+
+// Position returns where the lexer is in the input, just after the last token
+// it returned
+func (l *Lexer) Position() Position {
+	return l.currentPosition()
+}
+
+func (l *Lexer) currentPosition() Position {
+	return Position{Offset: l.position, Line: l.line, Column: l.column}
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return ch
 	}
 }
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	// combining marks can follow a letter, so a decomposed é is one letter
+	for isLetter(l.ch) || unicode.IsMark(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
@@ -165,45 +229,252 @@ func (l *Lexer) makeTwoCharToken(twoCharTokenType TokenType, oneCharTokenType To
 	}
 }
 
+// readNumber reads an integer, a float or a decimal. Integers can also be
+// written in hex, octal or binary after 0x, 0o or 0b, floats can have an
+// exponent as in 6.02e23 and digits can be grouped with _ as in 1_000_000.
 func (l *Lexer) readNumber() Token {
-	number := l.readInteger()
+	position := l.position
+	tokenType := TokenType(INT)
+
+	digit, prefixed := isDigit, false
+	if l.ch == '0' {
+		switch l.peekChar() {
+		case 'x', 'X':
+			digit, prefixed = isHexDigit, true
+		case 'o', 'O':
+			digit, prefixed = isOctalDigit, true
+		case 'b', 'B':
+			digit, prefixed = isBinaryDigit, true
+		}
+	}
+	if prefixed {
+		l.readChar()
+		l.readChar()
+	}
+
+	count, separated := l.readDigits(digit)
+	if prefixed && count == 0 && !isLetter(l.ch) && !isDigit(l.ch) && l.ch != '_' {
+		return Token{Type: ERROR, Literal: fmt.Sprintf("number %s has no digits", l.input[position:l.position])}
+	}
+
+	if !prefixed && l.ch == '.' && isDigit(l.peekChar()) {
+		l.readChar()
+		_, ok := l.readDigits(isDigit)
+		separated = separated && ok
+		tokenType = FLOAT
+	}
+
+	exponent := !prefixed && (l.ch == 'e' || l.ch == 'E')
+	if exponent {
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
 
-	if l.ch == '.' && isDigit(l.peekChar()) {
+		count, ok := l.readDigits(isDigit)
+		if count == 0 {
+			return l.malformedNumber(position, "exponent of %s has no digits")
+		}
+		separated = separated && ok
+		tokenType = FLOAT
+	}
+
+	// a 'd' right after the digits makes the number an exact decimal
+	if !prefixed && !exponent && l.ch == 'd' && !isLetter(l.peekChar()) && !isDigit(l.peekChar()) {
 		l.readChar()
-		number += "." + l.readInteger()
-		return Token{Type: FLOAT, Literal: number}
+		tokenType = DECIMAL
+	}
+
+	switch {
+	case l.ch == '.' && isDigit(l.peekChar()):
+		return l.malformedNumber(position, "%s has more than one decimal point")
+	case isLetter(l.ch) || isDigit(l.ch) || l.ch == '_':
+		return l.malformedNumber(position, fmt.Sprintf("invalid digit %q in %%s", l.ch))
+	case !separated:
+		return Token{Type: ERROR, Literal: fmt.Sprintf("_ has to sit between digits in %s", l.input[position:l.position])}
 	}
 
-	return Token{Type: INT, Literal: number}
+	return Token{Type: tokenType, Literal: l.input[position:l.position]}
 }
 
-func (l *Lexer) readInteger() string {
-	position := l.position
-	for isDigit(l.ch) {
+// readDigits reads digits and the _ grouping them. It returns how many digits
+// there were and whether every _ sat between two of them.
+func (l *Lexer) readDigits(digit func(rune) bool) (count int, separated bool) {
+	separated = true
+	afterDigit := false
+
+	for digit(l.ch) || l.ch == '_' {
+		if l.ch == '_' {
+			separated = separated && afterDigit && digit(l.peekChar())
+			afterDigit = false
+		} else {
+			count++
+			afterDigit = true
+		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	return count, separated
+}
+
+// malformedNumber reads the rest of a broken number that started at position
+// so it is reported once, and formats the message with the whole of it
+func (l *Lexer) malformedNumber(position int, format string) Token {
+	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '_' || l.ch == '.' && isDigit(l.peekChar()) {
+		l.readChar()
+	}
+	return Token{Type: ERROR, Literal: fmt.Sprintf(format, l.input[position:l.position])}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType TokenType, ch byte) Token {
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
+
+func isOctalDigit(ch rune) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func newToken(tokenType TokenType, ch rune) Token {
 	return Token{Type: tokenType, Literal: string(ch)}
 }
 
-func (l *Lexer) readString() Token {
-	position := l.position + 1
+// readLineComment reads a comment from \\ to the end of the line
+func (l *Lexer) readLineComment() Token {
+	position := l.position
+	for l.peekChar() != '\n' && l.peekChar() != '\r' && l.peekChar() != 0 {
+		l.readChar()
+	}
+	return Token{Type: COMMENT, Literal: l.input[position:l.readPosition]}
+}
+
+// readBlockComment reads a comment from */ to the next /*, comments don't nest
+func (l *Lexer) readBlockComment() Token {
+	position := l.position
+	l.readChar()
+
+	for {
+		l.readChar()
+		if l.ch == 0 {
+			return Token{Type: ERROR, Literal: "unterminated comment"}
+		}
+		if l.ch == '/' && l.peekChar() == '*' {
+			l.readChar()
+			return Token{Type: COMMENT, Literal: l.input[position:l.readPosition]}
+		}
+	}
+}
+
+// readString reads a string from the $ at pos to the next unescaped $ and
+// decodes its escape sequences. It can span lines.
+func (l *Lexer) readString(pos Position) Token {
+	var value strings.Builder
+	var escapeError *Token
+
 	for {
 		l.readChar()
-		if l.ch == '$' || l.ch == 0 {
-			break
+
+		switch l.ch {
+		case '$':
+			l.readChar()
+			if escapeError != nil {
+				return *escapeError
+			}
+			return Token{Type: STRING, Literal: value.String(), Pos: pos}
+		case 0:
+			return Token{Type: ERROR, Literal: "unterminated string", Pos: pos}
+		case '\\':
+			escapePos := l.currentPosition()
+			r, msg := l.readEscape()
+			if msg != "" && escapeError == nil {
+				escapeError = &Token{Type: ERROR, Literal: msg, Pos: escapePos}
+			}
+			value.WriteRune(r)
+		default:
+			value.WriteRune(l.ch)
+		}
+	}
+}
+
+// readEscape reads the escape sequence starting at the \ under the cursor and
+// returns the character it stands for, or a message saying what is wrong
+func (l *Lexer) readEscape() (rune, string) {
+	switch l.peekChar() {
+	case '$', '\\':
+		l.readChar()
+		return l.ch, ""
+	case 'n':
+		l.readChar()
+		return '\n', ""
+	case 't':
+		l.readChar()
+		return '\t', ""
+	case 'r':
+		l.readChar()
+		return '\r', ""
+	case 'u':
+		return l.readUnicodeEscape()
+	case 0:
+		// the string is unterminated, which is reported instead
+		return utf8.RuneError, ""
+	default:
+		l.readChar()
+		return utf8.RuneError, "unknown escape sequence \\" + string(l.ch)
+	}
+}
+
+// readUnicodeEscape reads \u{XXXX}, a code point in one to six hex digits
+func (l *Lexer) readUnicodeEscape() (rune, string) {
+	l.readChar()
+	if l.peekChar() != '{' {
+		return utf8.RuneError, "unicode escape has to be written as \\u{XXXX}"
+	}
+	l.readChar()
+
+	start := l.readPosition
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+	}
+	digits := l.input[start:l.readPosition]
+
+	if l.peekChar() != '}' || len(digits) == 0 || len(digits) > 6 {
+		return utf8.RuneError, "unicode escape has to be written as \\u{XXXX}"
+	}
+	l.readChar()
+
+	code, _ := strconv.ParseUint(digits, 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		return utf8.RuneError, "invalid code point \\u{" + digits + "}"
+	}
+	return rune(code), ""
+}
+
+// readRawString reads a string from the $$$ at pos to the next $$$ as it is
+// written, it can span lines and has no escape sequences
+func (l *Lexer) readRawString(pos Position) Token {
+	start := l.position + len("$$$")
+	end := strings.Index(l.input[start:], "$$$")
+
+	if end < 0 {
+		for l.ch != 0 {
+			l.readChar()
 		}
+		return Token{Type: ERROR, Literal: "unterminated raw string", Pos: pos}
+	}
+
+	for l.position < start+end+len("$$$") {
+		l.readChar()
 	}
-	return Token{Type: STRING, Literal: l.input[position:l.position]}
+	return Token{Type: RAW_STRING, Literal: l.input[start : start+end], Pos: pos}
 }
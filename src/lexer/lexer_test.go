@@ -135,7 +135,7 @@ func TestNextToken(t *testing.T) {
 
 		{FLOAT, "1.2"},
 		{INT, "1"},
-		{ILLEGAL, "."},
+		{DOT, "."},
 		{IDENT, "x"},
 	}
 
@@ -183,3 +183,378 @@ func TestSaveRestoreLexer(t *testing.T) {
 		t.Fatalf("expected +, got %q", tok.Literal)
 	}
 }
+
+func TestNestedSaveRestoreLexer(t *testing.T) {
+	l := New("1 2 3 4")
+
+	l.MoveReaderForTemp(func() {
+		l.NextToken()
+		l.MoveReaderForTemp(func() {
+			if tok := l.NextToken(); tok.Literal != "2" {
+				t.Fatalf("expected 2, got %q", tok.Literal)
+			}
+		})
+
+		if tok := l.NextToken(); tok.Literal != "2" {
+			t.Fatalf("expected 2 after the inner lookahead, got %q", tok.Literal)
+		}
+	})
+
+	if tok := l.NextToken(); tok.Literal != "1" {
+		t.Fatalf("expected 1 after the outer lookahead, got %q", tok.Literal)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `,5 = five let
+{five}print
+  $hi$`
+
+	tests := []struct {
+		expectedType   TokenType
+		expectedLine   int
+		expectedColumn int
+		expectedOffset int
+	}{
+		{COMMA, 1, 1, 0},
+		{INT, 1, 2, 1},
+		{ASSIGN, 1, 4, 3},
+		{IDENT, 1, 6, 5},
+		{LET, 1, 11, 10},
+		{LBRACE, 2, 1, 14},
+		{IDENT, 2, 2, 15},
+		{RBRACE, 2, 6, 19},
+		{IDENT, 2, 7, 20},
+		{STRING, 3, 3, 28},
+		{EOF, 3, 7, 32},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%s",
+				i, tt.expectedLine, tt.expectedColumn, tok.Pos)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - offset wrong. expected=%d, got=%d",
+				i, tt.expectedOffset, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestLoopKeywords(t *testing.T) {
+	input := `{x; arr} for [ ,break ] {x} while [ ,continue ]`
+
+	tests := []TokenType{LBRACE, IDENT, SEMICOLON, IDENT, RBRACE, FOR, LSQBRAC, COMMA, BREAK, RSQBRAC, LBRACE, IDENT, RBRACE, WHILE, LSQBRAC, COMMA, CONTINUE, RSQBRAC, EOF}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
+
+func TestImportTokens(t *testing.T) {
+	input := `,$utils.al$ = u import ,{1.5}u.add`
+
+	tests := []TokenType{COMMA, STRING, ASSIGN, IDENT, IMPORT, COMMA, LBRACE, FLOAT, RBRACE, IDENT, DOT, IDENT, EOF}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
+
+func TestDecimalTokens(t *testing.T) {
+	input := `1.50d 5d 5 dx 1.5 day`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{DECIMAL, "1.50d"},
+		{DECIMAL, "5d"},
+		{INT, "5"},
+		{IDENT, "dx"},
+		{FLOAT, "1.5"},
+		{IDENT, "day"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumberTokens(t *testing.T) {
+	input := `0xFF 0o17 0b1010 1_000_000 6.02e23 1E-5 2.5e+3 1_000.5d 0x1Fd
+0x 0b102 0o8 12ab 1.2.3 1e 1e+x 1__0 1_ 0x_1 1e3d 7`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{INT, "0xFF"},
+		{INT, "0o17"},
+		{INT, "0b1010"},
+		{INT, "1_000_000"},
+		{FLOAT, "6.02e23"},
+		{FLOAT, "1E-5"},
+		{FLOAT, "2.5e+3"},
+		{DECIMAL, "1_000.5d"},
+		{INT, "0x1Fd"},
+		{ERROR, "number 0x has no digits"},
+		{ERROR, "invalid digit '2' in 0b102"},
+		{ERROR, "invalid digit '8' in 0o8"},
+		{ERROR, "invalid digit 'a' in 12ab"},
+		{ERROR, "1.2.3 has more than one decimal point"},
+		{ERROR, "exponent of 1e has no digits"},
+		{ERROR, "exponent of 1e+x has no digits"},
+		{ERROR, "_ has to sit between digits in 1__0"},
+		{ERROR, "_ has to sit between digits in 1_"},
+		{ERROR, "_ has to sit between digits in 0x_1"},
+		{ERROR, "invalid digit 'd' in 1e3d"},
+		{INT, "7"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestArithmeticTokens(t *testing.T) {
+	input := `2 ** 3 // 4 *= 5 /= 6 * 7 / 8`
+
+	tests := []TokenType{INT, POWER, INT, INT_DIV, INT, ASTER_EQ, INT, SLASH_EQ, INT, ASTERISK, INT, SLASH, INT, EOF}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
+
+func TestNullishToken(t *testing.T) {
+	input := `(a)h ?? 0 ? ?`
+
+	tests := []TokenType{LPAREN, IDENT, RPAREN, IDENT, NULLISH, INT, ILLEGAL, ILLEGAL, EOF}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `\\ adds two numbers
+,1 + 2 \\ three
+,*/ a block
+comment /* 4 * 5 */ short /*
+,6 // 2
+*/ never closed`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{COMMENT, `\\ adds two numbers`, 1},
+		{COMMA, ",", 2},
+		{INT, "1", 2},
+		{PLUS, "+", 2},
+		{INT, "2", 2},
+		{COMMENT, `\\ three`, 2},
+		{COMMA, ",", 3},
+		{COMMENT, "*/ a block\ncomment /*", 3},
+		{INT, "4", 4},
+		{ASTERISK, "*", 4},
+		{INT, "5", 4},
+		{COMMENT, "*/ short /*", 4},
+		{COMMA, ",", 5},
+		{INT, "6", 5},
+		{INT_DIV, "//", 5},
+		{INT, "2", 5},
+		{ERROR, "unterminated comment", 6},
+		{EOF, "", 6},
+	}
+
+	l := NewWithComments(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d", i, tt.expectedLine, tok.Pos.Line)
+		}
+	}
+}
+
+func TestCommentsAreSkipped(t *testing.T) {
+	input := "\\\\ comment\n,1 */ two /* + \\\\ \\\\\r\n2 \\\\"
+
+	tests := []TokenType{COMMA, INT, PLUS, INT, EOF}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	input := `$a \$5 \\ bill$
+$tab\there\nnew line\r$
+$\u{48}\u{1F600}$
+$$$C:\new\$file$$$
+$$$two
+lines$$$ $$
+$bad \q escape$ $\u{110000}$ $\u12$
+$two
+lines$ $x$
+$$$never closed either`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{STRING, "a $5 \\ bill", 1, 1},
+		{STRING, "tab\there\nnew line\r", 2, 1},
+		{STRING, "H\U0001F600", 3, 1},
+		{RAW_STRING, `C:\new\$file`, 4, 1},
+		{RAW_STRING, "two\nlines", 5, 1},
+		{STRING, "", 6, 10},
+		{ERROR, "unknown escape sequence \\q", 7, 6},
+		{ERROR, "invalid code point \\u{110000}", 7, 18},
+		{ERROR, "unicode escape has to be written as \\u{XXXX}", 7, 31},
+		{STRING, "two\nlines", 8, 1},
+		{STRING, "x", 9, 8},
+		{ERROR, "unterminated raw string", 10, 1},
+		{EOF, "", 10, 23},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%s", i, tt.expectedLine, tt.expectedColumn, tok.Pos)
+		}
+	}
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"$never closed", "unterminated string"},
+		{"$never\nclosed \\$", "unterminated string"},
+		{"$$$never\nclosed $$", "unterminated raw string"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != ERROR || tok.Literal != tt.expected || tok.Pos.Line != 1 || tok.Pos.Column != 1 {
+			t.Errorf("%q: expected ERROR %q at 1:1, got %s %q at %s", tt.input, tt.expected, tok.Type, tok.Literal, tok.Pos)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := ",$héllo 😀$ = grüße let\n,{日本; café}add 😀 ,$👍🏽$"
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{COMMA, ",", 1, 1},
+		{STRING, "héllo 😀", 1, 2},
+		{ASSIGN, "=", 1, 12},
+		{IDENT, "grüße", 1, 14},
+		{LET, "let", 1, 20},
+		{COMMA, ",", 2, 1},
+		{LBRACE, "{", 2, 2},
+		{IDENT, "日本", 2, 3},
+		{SEMICOLON, ";", 2, 5},
+		{IDENT, "café", 2, 7},
+		{RBRACE, "}", 2, 12},
+		{IDENT, "add", 2, 13},
+		{ILLEGAL, "😀", 2, 17},
+		{COMMA, ",", 2, 19},
+		{STRING, "👍🏽", 2, 20},
+		{EOF, "", 2, 24},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%s", i, tt.expectedLine, tt.expectedColumn, tok.Pos)
+		}
+	}
+}
@@ -1,16 +1,22 @@
 package lexer
 
+import "fmt"
+
 type TokenType string
 
 const (
 	ILLEGAL = "ILLEGAL"
+	ERROR   = "ERROR" // a malformed literal, the literal is the message
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // \\ to the end of the line, or */ to /*
 
 	// Identifiers + literals
-	IDENT  = "IDENT"  // add, foobar, x, y, ...
-	INT    = "INT"    // 1343456
-	FLOAT  = "FLOAT"  // 134.3456
-	STRING = "STRING" // "foobar"
+	IDENT      = "IDENT"      // add, foobar, x, y, ...
+	INT        = "INT"        // 1343456
+	FLOAT      = "FLOAT"      // 134.3456
+	DECIMAL    = "DECIMAL"    // 134.3456d
+	STRING     = "STRING"     // $foobar$
+	RAW_STRING = "RAW_STRING" // $$$foo\bar$$$
 
 	// Operators
 	ASSIGN   = "="
@@ -20,6 +26,8 @@ const (
 	ASTERISK = "*"
 	SLASH    = "/"
 	MOD      = "%"
+	POWER    = "**"
+	INT_DIV  = "//"
 
 	LT = "<"
 	GT = ">"
@@ -35,10 +43,12 @@ const (
 
 	LOG_AND = "&&"
 	LOG_OR  = "||"
+	NULLISH = "??"
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	DOT       = "."
 
 	LPAREN  = "("
 	RPAREN  = ")"
@@ -56,22 +66,54 @@ const (
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
 	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	IMPORT   = "IMPORT"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+}
+
+// Position is the location of a token in the source
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number in characters, starting at 1
+}
+
+// IsValid reports whether the position points into the source
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 var keywords = map[string]TokenType{
-	"func":   FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"while":  WHILE,
+	"func":     FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"import":   IMPORT,
+	"try":      TRY,
+	"catch":    CATCH,
 }
 
 func Keywords() []TokenType {
@@ -82,6 +124,12 @@ func Keywords() []TokenType {
 		ELSE,
 		RETURN,
 		WHILE,
+		FOR,
+		BREAK,
+		CONTINUE,
+		IMPORT,
+		TRY,
+		CATCH,
 	}
 }
 
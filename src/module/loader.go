@@ -0,0 +1,130 @@
+// Package module loads the files pulled in by import statements. Every file
+// is evaluated once in its own environment and cached, later imports of the
+// same file share the module.
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/object"
+	"github.com/SirusCodes/anti-lang/src/parser"
+)
+
+// ExecFunc runs a parsed file in env with one of the engines, evaluator.Eval
+// or vm.Exec
+type ExecFunc func(program ast.Node, env *object.Environment) object.Object
+
+type Loader struct {
+	exec    ExecFunc
+	modules map[string]*object.Module // by absolute path
+	loading []string                  // files being evaluated, outermost first
+	// shared by every module of the program
+	streams  *object.Streams
+	builtins map[string]*object.Builtin
+	checked  bool
+}
+
+func NewLoader(exec ExecFunc) *Loader {
+	return &Loader{exec: exec, modules: map[string]*object.Module{}}
+}
+
+// Main prepares env to run the file at path, the entry point of the program.
+// Imports made by the file resolve relative to it, an empty path resolves
+// them from the working directory like the REPL does. It replaces the entry
+// point of the previous run, which may be imported from now on.
+func (l *Loader) Main(path string, env *object.Environment) {
+	l.loading = nil
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		// the entry file never finishes loading, importing it is a cycle
+		l.loading = append(l.loading, path)
+	}
+
+	env.SetModule(path, l)
+	l.streams = env.Streams()
+	l.builtins = env.Builtins()
+	l.checked = env.CheckedArithmetic()
+}
+
+// Import returns the module at path, evaluating it on the first import
+func (l *Loader) Import(path, from string) object.Object {
+	abs, err := resolve(path, from)
+	if err != nil {
+		return newError("cannot import %s: %s", path, err)
+	}
+
+	if module, ok := l.modules[abs]; ok {
+		return module
+	}
+
+	for i, loading := range l.loading {
+		if loading == abs {
+			return newError("import cycle: %s", cycle(append(l.loading[i:], abs)))
+		}
+	}
+
+	source, err := os.ReadFile(abs)
+	if err != nil {
+		return newError("cannot import %s: %s", path, err)
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return newError("%s:%s", path, errors[0])
+	}
+
+	env := object.NewEnvironment()
+	env.SetModule(abs, l)
+	env.SetStreams(l.streams)
+	env.SetBuiltins(l.builtins)
+	env.SetCheckedArithmetic(l.checked)
+
+	l.loading = append(l.loading, abs)
+	result := l.exec(program, env)
+	l.loading = l.loading[:len(l.loading)-1]
+
+	if err, ok := result.(*object.Error); ok {
+		// errors of the module itself would otherwise point into the importer
+		if strings.HasPrefix(err.Message, "import cycle: ") {
+			return newError("%s", err.Message)
+		}
+		return &object.Error{Message: fmt.Sprintf("%s:%s: %s", path, err.Pos, err.Message), Kind: err.Kind}
+	}
+
+	module := &object.Module{Name: name(abs), Path: abs, Env: env}
+	l.modules[abs] = module
+
+	return module
+}
+
+func resolve(path, from string) (string, error) {
+	if !filepath.IsAbs(path) && from != "" {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	return filepath.Abs(path)
+}
+
+func name(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func cycle(paths []string) string {
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+	return strings.Join(names, " -> ")
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: "ImportError"}
+}
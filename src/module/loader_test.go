@@ -0,0 +1,178 @@
+package module_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/src/evaluator"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/module"
+	"github.com/SirusCodes/anti-lang/src/object"
+	"github.com/SirusCodes/anti-lang/src/parser"
+	"github.com/SirusCodes/anti-lang/src/vm"
+)
+
+var engines = map[string]module.ExecFunc{
+	"tree": evaluator.Eval,
+	"vm":   vm.Exec,
+}
+
+// runFiles writes files into a temporary directory and runs main.al from it
+func runFiles(t *testing.T, exec module.ExecFunc, files map[string]string) object.Object {
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := parser.New(lexer.New(files["main.al"]))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %q", p.Errors())
+	}
+
+	env := object.NewEnvironment()
+	module.NewLoader(exec).Main(filepath.Join(dir, "main.al"), env)
+
+	return exec(program, env)
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			"bindings",
+			map[string]string{
+				"main.al":  ",$utils.al$ import\n,{utils.ten; 5}utils.add",
+				"utils.al": "{a; b} add func [ ,a + b return ]\n,10 = ten let",
+			},
+			"15",
+		},
+		{
+			"alias",
+			map[string]string{
+				"main.al":  ",$utils.al$ = u import\n,u.ten",
+				"utils.al": ",10 = ten let",
+			},
+			"10",
+		},
+		{
+			"relative to the importing file",
+			map[string]string{
+				"main.al":  ",$lib/a.al$ import\n,a.value",
+				"lib/a.al": ",$b.al$ import\n,b.value + 1 = value let",
+				"lib/b.al": ",41 = value let",
+				"b.al":     ",0 = value let",
+			},
+			"42",
+		},
+		{
+			"evaluated once",
+			map[string]string{
+				"main.al":  ",$count.al$ import\n,$./count.al$ = again import\n,again == count",
+				"count.al": ",0 = n let",
+			},
+			"true",
+		},
+		{
+			"module is a value",
+			map[string]string{
+				"main.al":  ",$utils.al$ import\n,utils",
+				"utils.al": "",
+			},
+			"module utils",
+		},
+		{
+			"missing member",
+			map[string]string{
+				"main.al":  ",$utils.al$ import\n,utils.nope",
+				"utils.al": "",
+			},
+			"ERROR: module utils has no member nope",
+		},
+		{
+			"member of something else",
+			map[string]string{
+				"main.al": ",5 = x let\n,x.y",
+			},
+			"ERROR: member access not supported: INTEGER",
+		},
+		{
+			"missing file",
+			map[string]string{
+				"main.al": ",$nope.al$ import",
+			},
+			"ERROR: cannot import nope.al: open",
+		},
+		{
+			"cycle",
+			map[string]string{
+				"main.al": ",$a.al$ import",
+				"a.al":    ",$b.al$ import",
+				"b.al":    ",$a.al$ import",
+			},
+			"ERROR: import cycle: a.al -> b.al -> a.al",
+		},
+		{
+			"cycle through the entry file",
+			map[string]string{
+				"main.al": ",$a.al$ import",
+				"a.al":    ",$main.al$ import",
+			},
+			"ERROR: import cycle: main.al -> a.al -> main.al",
+		},
+		{
+			"runtime error in module",
+			map[string]string{
+				"main.al": ",$bad.al$ import",
+				"bad.al":  ",1 = x let\n,x + $a$ - 1",
+			},
+			"ERROR: bad.al:2:",
+		},
+		{
+			"parse error in module",
+			map[string]string{
+				"main.al": ",$bad.al$ import",
+				"bad.al":  ",1 = let",
+			},
+			"ERROR: bad.al:1:",
+		},
+	}
+
+	for name, exec := range engines {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				result := runFiles(t, exec, tt.files)
+				if result == nil {
+					t.Errorf("%s: got nil", tt.name)
+					continue
+				}
+
+				got := result.Inspect()
+				if !strings.HasPrefix(got, tt.expected) {
+					t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestImportWithoutLoader(t *testing.T) {
+	p := parser.New(lexer.New(",$utils.al$ import"))
+	program := p.ParseProgram()
+
+	result := evaluator.Eval(program, object.NewEnvironment())
+	if result.Inspect() != "ERROR: import is not supported here" {
+		t.Errorf("unexpected result %q", result.Inspect())
+	}
+}
@@ -1,13 +1,52 @@
 package object
 
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
 type Environment struct {
-	store map[string]Object
+	// every name has a slot so compiled code can reach it by index, a nil
+	// slot is a name that was resolved but not set yet
+	names map[string]int
+	slots []Object
 	outer *Environment
+
+	// file and importer are set on the top-level environment of a file so
+	// import statements can resolve paths relative to it
+	file     string
+	importer Importer
+
+	// set on the top-level environment of a program
+	streams           *Streams
+	builtins          map[string]*Builtin
+	checkedArithmetic bool
+
+	// how many function calls deep the environment of a call is
+	callDepth int
+}
+
+// Streams are the standard input and output of a program, builtins like
+// print use the ones of the environment they are called from
+type Streams struct {
+	Stdin  *bufio.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// DefaultStreams are the streams of the process, used by environments that
+// were not given any
+var DefaultStreams = &Streams{Stdin: bufio.NewReader(os.Stdin), Stdout: os.Stdout, Stderr: os.Stderr}
+
+// Importer loads the module at path, relative to the file importing it. It
+// returns a *Module or an *Error.
+type Importer interface {
+	Import(path, from string) Object
 }
 
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
-	return &Environment{store: s}
+	return &Environment{names: make(map[string]int)}
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
@@ -17,14 +56,115 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+	if slot, ok := e.names[name]; ok && e.slots[slot] != nil {
+		return e.slots[slot], true
 	}
-	return obj, ok
+	if e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return nil, false
 }
 
 func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
+	e.slots[e.Slot(name)] = val
 	return val
 }
+
+// Slot returns the slot of name in e, adding an empty one the first time
+func (e *Environment) Slot(name string) int {
+	slot, ok := e.names[name]
+	if !ok {
+		slot = len(e.slots)
+		e.names[name] = slot
+		e.slots = append(e.slots, nil)
+	}
+	return slot
+}
+
+// GetSlot returns the value in slot, nil when it was not set. Unlike Get it
+// does not look in the enclosing environments.
+func (e *Environment) GetSlot(slot int) Object {
+	return e.slots[slot]
+}
+
+func (e *Environment) SetSlot(slot int, val Object) {
+	e.slots[slot] = val
+}
+
+// SetModule marks e as the top-level environment of file, an empty file
+// resolves imports from the working directory
+func (e *Environment) SetModule(file string, importer Importer) {
+	e.file = file
+	e.importer = importer
+}
+
+// Module returns the file and importer of the closest enclosing module,
+// importer is nil when imports are not available
+func (e *Environment) Module() (string, Importer) {
+	for env := e; env != nil; env = env.outer {
+		if env.importer != nil {
+			return env.file, env.importer
+		}
+	}
+	return "", nil
+}
+
+// SetStreams sets the streams of the program running in e
+func (e *Environment) SetStreams(streams *Streams) {
+	e.streams = streams
+}
+
+// Streams returns the streams of the closest enclosing environment that has
+// them, or DefaultStreams
+func (e *Environment) Streams() *Streams {
+	for env := e; env != nil; env = env.outer {
+		if env.streams != nil {
+			return env.streams
+		}
+	}
+	return DefaultStreams
+}
+
+// SetBuiltins sets the builtins only the program running in e can call, on
+// top of the ones every program has
+func (e *Environment) SetBuiltins(builtins map[string]*Builtin) {
+	e.builtins = builtins
+}
+
+// Builtins returns the builtins of the closest enclosing environment that has
+// them, or nil
+func (e *Environment) Builtins() map[string]*Builtin {
+	for env := e; env != nil; env = env.outer {
+		if env.builtins != nil {
+			return env.builtins
+		}
+	}
+	return nil
+}
+
+// SetCheckedArithmetic makes integer overflow an error for the program running
+// in e instead of letting the value grow into a BigInt
+func (e *Environment) SetCheckedArithmetic(on bool) {
+	e.checkedArithmetic = on
+}
+
+// CheckedArithmetic reports whether integer overflow is an error for the
+// program running in e, as set on its top-level environment
+func (e *Environment) CheckedArithmetic() bool {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e.checkedArithmetic
+}
+
+// SetCallDepth records how many calls deep e is, set on the environment of a
+// function call
+func (e *Environment) SetCallDepth(depth int) {
+	e.callDepth = depth
+}
+
+// CallDepth returns how many calls deep code running in e is, 0 outside of
+// any function
+func (e *Environment) CallDepth() int {
+	return e.callDepth
+}
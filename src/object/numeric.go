@@ -0,0 +1,206 @@
+package object
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"strings"
+)
+
+// DivisionScale is the number of digits after the point a decimal division
+// is rounded to when it does not terminate sooner
+const DivisionScale = 16
+
+var bigTen = big.NewInt(10)
+
+// BigInt is an integer too large for an Integer
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectTypes { return BIGINT_OBJ }
+func (b *BigInt) Inspect() string   { return b.Value.String() }
+
+// HashKey matches the key of an Integer with the same value
+func (b *BigInt) HashKey() HashKey {
+	if b.Value.IsInt64() {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(b.Value.Int64())}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// Decimal is an exact decimal number, Unscaled * 10^-Scale. The scale is
+// never negative and is kept from the literal, so 1.50d prints as 1.50.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+func (d *Decimal) Type() ObjectTypes { return DECIMAL_OBJ }
+
+func (d *Decimal) Inspect() string {
+	digits := new(big.Int).Abs(d.Unscaled).String()
+
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.Scale == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+
+	point := len(digits) - d.Scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// HashKey is the same for decimals that only differ in trailing zeros, and
+// matches the key of the equal integer when the decimal is integral
+func (d *Decimal) HashKey() HashKey {
+	normal := d.normalize()
+	if normal.Scale == 0 {
+		return (&BigInt{Value: normal.Unscaled}).HashKey()
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(normal.Inspect()))
+	return HashKey{Type: d.Type(), Value: h.Sum64()}
+}
+
+// ParseDecimal reads a decimal written as digits with an optional fraction
+// and sign, like -12.50
+func ParseDecimal(text string) (*Decimal, error) {
+	whole, fraction, _ := strings.Cut(text, ".")
+
+	unscaled, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok || strings.HasPrefix(fraction, "-") || strings.HasPrefix(fraction, "+") {
+		return nil, fmt.Errorf("could not parse %s as decimal", text)
+	}
+
+	return &Decimal{Unscaled: unscaled, Scale: len(fraction)}, nil
+}
+
+// NewDecimalFromInt returns value as a decimal without any fraction digits
+func NewDecimalFromInt(value *big.Int) *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Set(value), Scale: 0}
+}
+
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	a, b, scale := align(d, other)
+	return &Decimal{Unscaled: a.Add(a, b), Scale: scale}
+}
+
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	a, b, scale := align(d, other)
+	return &Decimal{Unscaled: a.Sub(a, b), Scale: scale}
+}
+
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Mul(d.Unscaled, other.Unscaled), Scale: d.Scale + other.Scale}
+}
+
+// Quo divides d by other, rounding half to even at DivisionScale digits.
+// The result drops trailing zeros. other must not be zero.
+func (d *Decimal) Quo(other *Decimal) *Decimal {
+	scale := max(DivisionScale, d.Scale, other.Scale)
+
+	// d / other = (d.Unscaled * 10^(scale - d.Scale + other.Scale) / other.Unscaled) * 10^-scale
+	numerator := new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale+other.Scale))
+	quotient, remainder := new(big.Int).QuoRem(numerator, other.Unscaled, new(big.Int))
+
+	// round half to even by comparing twice the remainder with the divisor
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	if cmp := twice.Cmp(new(big.Int).Abs(other.Unscaled)); cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1) {
+		if numerator.Sign()*other.Unscaled.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return (&Decimal{Unscaled: quotient, Scale: scale}).normalize()
+}
+
+// Rem is the remainder of truncated division, it has the sign of d. other
+// must not be zero.
+func (d *Decimal) Rem(other *Decimal) *Decimal {
+	a, b, scale := align(d, other)
+	return &Decimal{Unscaled: a.Rem(a, b), Scale: scale}
+}
+
+// QuoFloor is the largest whole number not greater than d / other. other
+// must not be zero.
+func (d *Decimal) QuoFloor(other *Decimal) *Decimal {
+	a, b, _ := align(d, other)
+	quotient, remainder := new(big.Int).QuoRem(a, b, new(big.Int))
+	if remainder.Sign() != 0 && (remainder.Sign() < 0) != (b.Sign() < 0) {
+		quotient.Sub(quotient, big.NewInt(1))
+	}
+	return &Decimal{Unscaled: quotient, Scale: 0}
+}
+
+// Pow raises d to a whole power, a negative power divides like Quo. d must
+// not be zero when n is negative.
+func (d *Decimal) Pow(n int64) *Decimal {
+	if n < 0 {
+		return NewDecimalFromInt(big.NewInt(1)).Quo(d.Pow(-n))
+	}
+
+	unscaled := new(big.Int).Exp(d.Unscaled, big.NewInt(n), nil)
+	return &Decimal{Unscaled: unscaled, Scale: d.Scale * int(n)}
+}
+
+// Int returns the value of d if it is a whole number
+func (d *Decimal) Int() (*big.Int, bool) {
+	n := d.normalize()
+	return n.Unscaled, n.Scale == 0
+}
+
+func (d *Decimal) Neg() *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Neg(d.Unscaled), Scale: d.Scale}
+}
+
+func (d *Decimal) Cmp(other *Decimal) int {
+	a, b, _ := align(d, other)
+	return a.Cmp(b)
+}
+
+func (d *Decimal) IsZero() bool {
+	return d.Unscaled.Sign() == 0
+}
+
+// normalize drops the trailing zeros of the fraction
+func (d *Decimal) normalize() *Decimal {
+	unscaled, scale := new(big.Int).Set(d.Unscaled), d.Scale
+	remainder := new(big.Int)
+
+	for scale > 0 {
+		quotient, r := new(big.Int).QuoRem(unscaled, bigTen, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled, scale = quotient, scale-1
+	}
+
+	return &Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+// align returns copies of the unscaled values of a and b brought to the same scale
+func align(a, b *Decimal) (*big.Int, *big.Int, int) {
+	scale := max(a.Scale, b.Scale)
+	x := new(big.Int).Mul(a.Unscaled, pow10(scale-a.Scale))
+	y := new(big.Int).Mul(b.Unscaled, pow10(scale-b.Scale))
+	return x, y, scale
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,118 @@
+package object
+
+import (
+	"math/big"
+	"testing"
+)
+
+func mustDecimal(t *testing.T, text string) *Decimal {
+	t.Helper()
+	d, err := ParseDecimal(text)
+	if err != nil {
+		t.Fatalf("ParseDecimal(%q) failed: %s", text, err)
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.50", "1.50"},
+		{"0.05", "0.05"},
+		{"-0.5", "-0.5"},
+		{"12", "12"},
+		{"007.10", "7.10"},
+	}
+
+	for _, tt := range tests {
+		if got := mustDecimal(t, tt.input).Inspect(); got != tt.expected {
+			t.Errorf("ParseDecimal(%q).Inspect() wrong. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"", "1.2.3", "1.-5", "abc"} {
+		if _, err := ParseDecimal(input); err == nil {
+			t.Errorf("ParseDecimal(%q) did not fail", input)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	tests := []struct {
+		left, op, right string
+		expected        string
+	}{
+		{"0.1", "+", "0.2", "0.3"},
+		{"1.50", "+", "1", "2.50"},
+		{"1", "-", "0.01", "0.99"},
+		{"1.5", "*", "1.5", "2.25"},
+		{"1", "/", "4", "0.25"},
+		{"1", "/", "3", "0.3333333333333333"},
+		{"-2", "/", "3", "-0.6666666666666667"},
+		// exact halves round to even
+		{"1", "/", "20000000000000000", "0"},
+		{"3", "/", "20000000000000000", "0.0000000000000002"},
+		{"0.00000000000000005", "/", "1", "0.00000000000000005"},
+		{"10.5", "%", "3", "1.5"},
+		{"-10.5", "%", "3", "-1.5"},
+	}
+
+	for _, tt := range tests {
+		left, right := mustDecimal(t, tt.left), mustDecimal(t, tt.right)
+
+		var result *Decimal
+		switch tt.op {
+		case "+":
+			result = left.Add(right)
+		case "-":
+			result = left.Sub(right)
+		case "*":
+			result = left.Mul(right)
+		case "/":
+			result = left.Quo(right)
+		case "%":
+			result = left.Rem(right)
+		}
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s %s %s wrong. expected=%q, got=%q", tt.left, tt.op, tt.right, tt.expected, result.Inspect())
+		}
+	}
+
+	if mustDecimal(t, "1.10").Cmp(mustDecimal(t, "1.1")) != 0 {
+		t.Errorf("1.10 and 1.1 are not equal")
+	}
+}
+
+func TestNumericHashKeys(t *testing.T) {
+	if mustDecimal(t, "1.10").HashKey() != mustDecimal(t, "1.1").HashKey() {
+		t.Errorf("equal decimals have different hash keys")
+	}
+
+	if mustDecimal(t, "1.1").HashKey() == mustDecimal(t, "1.2").HashKey() {
+		t.Errorf("different decimals have the same hash key")
+	}
+
+	if (&BigInt{Value: big.NewInt(5)}).HashKey() != (&Integer{Value: 5}).HashKey() {
+		t.Errorf("big integer and integer with the same value have different hash keys")
+	}
+
+	huge, _ := new(big.Int).SetString("99999999999999999999", 10)
+	if (&BigInt{Value: huge}).HashKey() == (&BigInt{Value: big.NewInt(5)}).HashKey() {
+		t.Errorf("different big integers have the same hash key")
+	}
+
+	if mustDecimal(t, "5.00").HashKey() != (&Integer{Value: 5}).HashKey() {
+		t.Errorf("integral decimal and integer with the same value have different hash keys")
+	}
+
+	if mustDecimal(t, "99999999999999999999.0").HashKey() != (&BigInt{Value: huge}).HashKey() {
+		t.Errorf("integral decimal and big integer with the same value have different hash keys")
+	}
+
+	if mustDecimal(t, "5.5").HashKey() == (&Integer{Value: 5}).HashKey() {
+		t.Errorf("decimal and integer with different values have the same hash key")
+	}
+}
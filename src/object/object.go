@@ -4,23 +4,36 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
 	"strings"
 
 	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/lexer"
 )
 
 const (
 	INTEGER_OBJ      = "INTEGER"
 	FLOAT_OBJ        = "FLOAT"
+	BIGINT_OBJ       = "BIGINT"
+	DECIMAL_OBJ      = "DECIMAL"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	MODULE_OBJ       = "MODULE"
+	EXCEPTION_OBJ    = "EXCEPTION"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	ITERATOR_OBJ          = "ITERATOR"
 )
 
 type ObjectTypes string
@@ -70,13 +83,59 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// Break and Continue unwind the blocks of a loop body up to the loop itself
+type Break struct{}
+
+func (b *Break) Type() ObjectTypes { return BREAK_OBJ }
+func (b *Break) Inspect() string   { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectTypes { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string   { return "continue" }
+
 type Error struct {
 	Message string
+	Kind    string // e.g. TypeError, empty for a plain Error
+	Pos     lexer.Position
+	Stack   []Frame // call frames the error unwound through, innermost first
 }
 
 func (e *Error) Type() ObjectTypes { return ERROR_OBJ }
 func (e *Error) Inspect() string   { return "ERROR: " + e.Message }
 
+// Exception is an error caught by try/catch. Unlike an Error it is a plain
+// value, it can be stored, inspected through its members or raised again.
+type Exception struct {
+	Err *Error
+}
+
+func (e *Exception) Type() ObjectTypes { return EXCEPTION_OBJ }
+func (e *Exception) Inspect() string   { return e.Kind() + ": " + e.Err.Message }
+
+func (e *Exception) Kind() string {
+	if e.Err.Kind == "" {
+		return "Error"
+	}
+	return e.Err.Kind
+}
+
+// Member returns the message, kind, line or column of the error
+func (e *Exception) Member(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &String{Value: e.Err.Message}, true
+	case "kind":
+		return &String{Value: e.Kind()}, true
+	case "line":
+		return &Integer{Value: int64(e.Err.Pos.Line)}, true
+	case "column":
+		return &Integer{Value: int64(e.Err.Pos.Column)}, true
+	default:
+		return nil, false
+	}
+}
+
 type Function struct {
 	Name       string
 	Token      *ast.Identifier
@@ -97,17 +156,88 @@ func (f *Function) Inspect() string {
 	out.WriteString("{")
 	out.WriteString(strings.Join(params, "; "))
 	out.WriteString("} ")
-	out.WriteString(f.Name)
-	out.WriteString(" ")
-	out.WriteString(f.Token.TokenLiteral())
-	out.WriteString(" ")
-	out.WriteString("[\n")
+	if f.Name != "" {
+		out.WriteString(f.Name)
+		out.WriteString(" ")
+	}
+	out.WriteString("func ")
 	out.WriteString(f.Body.String())
-	out.WriteString("\n]")
 
 	return out.String()
 }
 
+// CompiledFunction is a function body lowered to bytecode by the compiler
+type CompiledFunction struct {
+	Name         string
+	Parameters   []string
+	Locals       []string // names of the local slots, the parameters first
+	Instructions code.Instructions
+	Positions    map[int]lexer.Position // instruction offset to source position
+	Constants    []Object               // constant pool the instructions refer to
+}
+
+func (cf *CompiledFunction) Type() ObjectTypes { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure is a compiled function bound to the variables it was defined with
+type Closure struct {
+	Fn      *CompiledFunction
+	Globals *Globals
+	Outer   *Locals // nil for functions defined at the top level
+}
+
+func (c *Closure) Type() ObjectTypes { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string {
+	name := ""
+	if c.Fn.Name != "" {
+		name = c.Fn.Name + " "
+	}
+	return "{" + strings.Join(c.Fn.Parameters, "; ") + "} " + name + "func"
+}
+
+// Globals binds the global variables of a compiled program to the slots of
+// the environment it runs in
+type Globals struct {
+	Env   *Environment
+	Names []string // indexed like the global variables of the program
+	Slots []int    // slot in Env of each of them
+}
+
+func NewGlobals(names []string, env *Environment) *Globals {
+	slots := make([]int, len(names))
+	for i, name := range names {
+		slots[i] = env.Slot(name)
+	}
+	return &Globals{Env: env, Names: names, Slots: slots}
+}
+
+// Locals are the variables of a single call to a compiled function
+type Locals struct {
+	Slots []Object // nil until the variable is set
+	Fn    *CompiledFunction
+	Outer *Locals // locals of the call the function was defined in
+}
+
+// Get returns the local called name, it does not look in Outer
+func (l *Locals) Get(name string) (Object, bool) {
+	for i := len(l.Fn.Locals) - 1; i >= 0; i-- {
+		if l.Fn.Locals[i] == name && l.Slots[i] != nil {
+			return l.Slots[i], true
+		}
+	}
+	return nil, false
+}
+
+// Iterator steps through the values visited by a for loop
+type Iterator struct {
+	Next func() (Object, bool)
+}
+
+func (it *Iterator) Type() ObjectTypes { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string   { return "iterator" }
+
 type String struct {
 	Hashable
 	Value string
@@ -116,7 +246,18 @@ type String struct {
 func (s *String) Type() ObjectTypes { return STRING_OBJ }
 func (s *String) Inspect() string   { return s.Value }
 
-type BuiltinFunction func(args ...Object) Object
+// CallContext is handed to builtins by the engine running them, so builtins
+// like map can call back into AntiLang functions
+type CallContext struct {
+	// Call applies fn to args and returns its result, errors raised by fn are
+	// returned as they are and should be passed on
+	Call func(fn Object, args ...Object) Object
+
+	// Streams are where builtins like print and input write and read
+	Streams *Streams
+}
+
+type BuiltinFunction func(ctx *CallContext, args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
@@ -125,6 +266,16 @@ type Builtin struct {
 func (b *Builtin) Type() ObjectTypes { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string   { return "builtin function" }
 
+// Module is an imported file, its top-level bindings are read as module.name
+type Module struct {
+	Name string
+	Path string // absolute path of the file
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectTypes { return MODULE_OBJ }
+func (m *Module) Inspect() string   { return "module " + m.Name }
+
 type Array struct {
 	Elements []Object
 }
@@ -145,8 +296,59 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
+// Hash remembers the order its keys were first set in, always go through
+// Set and Delete so Keys stays in sync with Pairs
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey // insertion order of Pairs
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set adds the pair, a key that is already present keeps its position
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
+}
+
+func (h *Hash) Get(key HashKey) (HashPair, bool) {
+	pair, ok := h.Pairs[key]
+	return pair, ok
+}
+
+func (h *Hash) Delete(key HashKey) {
+	if _, ok := h.Pairs[key]; !ok {
+		return
+	}
+
+	delete(h.Pairs, key)
+	for i, k := range h.Keys {
+		if k == key {
+			h.Keys = append(h.Keys[:i:i], h.Keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Ordered returns the pairs in insertion order
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, len(h.Keys))
+	for i, key := range h.Keys {
+		pairs[i] = h.Pairs[key]
+	}
+	return pairs
+}
+
+func (h *Hash) Copy() *Hash {
+	copied := NewHash()
+	for _, key := range h.Keys {
+		copied.Set(key, h.Pairs[key])
+	}
+	return copied
 }
 
 func (h *Hash) Type() ObjectTypes { return HASH_OBJ }
@@ -154,7 +356,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Ordered() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -188,6 +390,20 @@ func (b *Boolean) HashKey() HashKey {
 func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
+
+// HashKey matches the key of the equal integer when the float is integral,
+// 1.0 and -0.0 find the values stored under 1 and 0
+func (f *Float) HashKey() HashKey {
+	value := f.Value
+	if value == math.Trunc(value) && !math.IsInf(value, 0) {
+		if value >= math.MinInt64 && value < math.MaxInt64 {
+			return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(value))}
+		}
+		integer, _ := big.NewFloat(value).Int(nil)
+		return (&BigInt{Value: integer}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(value)}
+}
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 
@@ -1,6 +1,12 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/src/lexer"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +26,96 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	if (&Float{Value: 1.5}).HashKey() != (&Float{Value: 1.5}).HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+
+	if (&Float{Value: 1.5}).HashKey() == (&Float{Value: 2.5}).HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+
+	if (&Float{Value: 1}).HashKey() != (&Integer{Value: 1}).HashKey() {
+		t.Errorf("integral float and integer with the same value have different hash keys")
+	}
+
+	if (&Float{Value: 1e20}).HashKey() != (&BigInt{Value: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}).HashKey() {
+		t.Errorf("integral float and big integer with the same value have different hash keys")
+	}
+
+	if (&Float{Value: 1.5}).HashKey() == (&Integer{Value: 1}).HashKey() {
+		t.Errorf("float and integer with different values share a hash key")
+	}
+
+	if (&Float{Value: math.Inf(1)}).HashKey() == (&Float{Value: math.Inf(-1)}).HashKey() {
+		t.Errorf("infinities of different signs share a hash key")
+	}
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	hash := NewHash()
+	for _, key := range []string{"c", "a", "b"} {
+		str := &String{Value: key}
+		hash.Set(str.HashKey(), HashPair{Key: str, Value: &Integer{Value: 1}})
+	}
+
+	a := &String{Value: "a"}
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+
+	if hash.Inspect() != "[c: 1; a: 2; b: 1]" {
+		t.Errorf("wrong order after update. got=%q", hash.Inspect())
+	}
+
+	hash.Delete(a.HashKey())
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 3}})
+
+	if hash.Inspect() != "[c: 1; b: 1; a: 3]" {
+		t.Errorf("wrong order after delete. got=%q", hash.Inspect())
+	}
+}
+
+func TestErrorTraceback(t *testing.T) {
+	err := &Error{
+		Message: "identifier not found: x",
+		Pos:     lexer.Position{Line: 2, Column: 8},
+		Stack: []Frame{
+			{Function: "inner", Pos: lexer.Position{Line: 5, Column: 6}},
+			{Function: "outer", Pos: lexer.Position{Line: 7, Column: 2}},
+		},
+	}
+
+	source := "{a} inner func [\n\t,{a + x}print\n]"
+
+	expected := `Traceback (innermost call last):
+  main.al:7:2: in call to outer
+  main.al:5:6: in call to inner
+main.al:2:8: ERROR: identifier not found: x
+    	,{a + x}print
+    	      ^
+`
+
+	if got := err.Traceback("main.al", source); got != expected {
+		t.Errorf("wrong traceback. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestErrorTracebackUnicode(t *testing.T) {
+	err := &Error{
+		Message: "identifier not found: ñ",
+		Pos:     lexer.Position{Line: 1, Column: 13},
+	}
+
+	expected := `main.al:1:13: ERROR: identifier not found: ñ
+    ,{$héllo😀$ + ñ}print
+                ^
+`
+
+	if got := err.Traceback("main.al", ",{$héllo😀$ + ñ}print"); got != expected {
+		t.Errorf("wrong traceback. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
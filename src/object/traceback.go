@@ -0,0 +1,73 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/SirusCodes/anti-lang/src/lexer"
+)
+
+// Frame is a single function call the error passed through
+type Frame struct {
+	Function string
+	Pos      lexer.Position // position of the call site
+}
+
+// Traceback renders the error together with its call stack, innermost frame
+// last, and underlines the offending line of source when it is available.
+func (e *Error) Traceback(filename, source string) string {
+	var out bytes.Buffer
+
+	location := func(pos lexer.Position) string {
+		if filename == "" {
+			return pos.String()
+		}
+		return filename + ":" + pos.String()
+	}
+
+	if len(e.Stack) > 0 {
+		out.WriteString("Traceback (innermost call last):\n")
+		for i := len(e.Stack) - 1; i >= 0; i-- {
+			frame := e.Stack[i]
+			name := frame.Function
+			if name == "" {
+				name = "<anonymous>"
+			}
+			out.WriteString(fmt.Sprintf("  %s: in call to %s\n", location(frame.Pos), name))
+		}
+	}
+
+	out.WriteString(location(e.Pos) + ": " + e.Inspect() + "\n")
+
+	// columns count characters, not bytes
+	if line, ok := sourceLine(source, e.Pos.Line); ok && e.Pos.Column <= len([]rune(line))+1 {
+		out.WriteString("    " + line + "\n")
+		out.WriteString("    " + underline(string([]rune(line)[:e.Pos.Column-1])) + "^\n")
+	}
+
+	return out.String()
+}
+
+func sourceLine(source string, line int) (string, bool) {
+	if source == "" || line < 1 {
+		return "", false
+	}
+
+	lines := strings.Split(source, "\n")
+	if line > len(lines) {
+		return "", false
+	}
+
+	return strings.TrimRight(lines[line-1], "\r"), true
+}
+
+// underline keeps tabs so the caret lines up with the source line
+func underline(prefix string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return '\t'
+		}
+		return ' '
+	}, prefix)
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/SirusCodes/anti-lang/src/ast"
 	"github.com/SirusCodes/anti-lang/src/lexer"
@@ -13,7 +16,7 @@ func (parser *Parser) parseExpression(precedence int, endToken lexer.TokenType)
 	prefix := parser.prefixParseFns[parser.curToken.Type]
 	if prefix == nil {
 		msg := fmt.Sprintf("no prefix parse function for %s", parser.curToken.Type)
-		parser.errors = append(parser.errors, msg)
+		parser.addGenericError(msg)
 		return nil
 	}
 	leftExp := prefix()
@@ -51,6 +54,10 @@ func (parser *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := parser.curPrecedence()
+	// ** is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	if ie.Token.Type == lexer.POWER {
+		precedence--
+	}
 	parser.nextToken()
 	ie.Right = parser.parseExpression(precedence, lexer.SEMICOLON)
 
@@ -62,9 +69,16 @@ func (parser *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(parser.curToken.Literal, 0, 64)
 
+	// too large for an int64, it becomes a big integer instead
+	if errors.Is(err, strconv.ErrRange) {
+		if value, ok := new(big.Int).SetString(parser.curToken.Literal, 0); ok {
+			return &ast.BigIntLiteral{Token: parser.curToken, Value: value}
+		}
+	}
+
 	if err != nil {
 		msg := "could not parse " + parser.curToken.Literal + " as integer"
-		parser.errors = append(parser.errors, msg)
+		parser.addGenericError(msg)
 		return nil
 	}
 
@@ -72,6 +86,11 @@ func (parser *Parser) parseIntegerLiteral() ast.Expression {
 	return itl
 }
 
+func (parser *Parser) parseDecimalLiteral() ast.Expression {
+	value := strings.ReplaceAll(strings.TrimSuffix(parser.curToken.Literal, "d"), "_", "")
+	return &ast.DecimalLiteral{Token: parser.curToken, Value: value}
+}
+
 func (parser *Parser) parseFloatLiteral() ast.Expression {
 	ftl := &ast.FloatLiteral{Token: parser.curToken}
 
@@ -79,7 +98,7 @@ func (parser *Parser) parseFloatLiteral() ast.Expression {
 
 	if err != nil {
 		msg := "could not parse " + parser.curToken.Literal + " as float"
-		parser.errors = append(parser.errors, msg)
+		parser.addGenericError(msg)
 		return nil
 	}
 
@@ -95,10 +114,7 @@ func (parser *Parser) parseLBraceExpression() ast.Expression {
 	var token lexer.Token
 	var isFuncDef bool
 	parser.peekTokenTemp(func() {
-		for !parser.curTokenIs(lexer.RBRACE) && !parser.curTokenIs(lexer.EOF) {
-			parser.nextToken()
-		}
-
+		parser.skipToMatching(lexer.LBRACE, lexer.RBRACE)
 		parser.nextToken()
 
 		token = parser.curToken
@@ -111,10 +127,14 @@ func (parser *Parser) parseLBraceExpression() ast.Expression {
 			return parser.parseFunctionExpression()
 		}
 		return parser.parseCallExpression()
+	case lexer.FUNCTION:
+		return parser.parseFunctionLiteral()
 	case lexer.IF:
 		return parser.parseConditionalExpression()
 	case lexer.WHILE:
 		return parser.parseWhileExpression()
+	case lexer.FOR:
+		return parser.parseForExpression()
 	default:
 		return parser.parseGroupedExpression()
 	}
@@ -136,11 +156,40 @@ func (parser *Parser) parseFunctionExpression() ast.Expression {
 		return nil
 	}
 
-	fe.Body = parser.parseBlockStatement()
+	fe.Body = parser.parseFunctionBody()
 
 	return fe
 }
 
+func (parser *Parser) parseFunctionLiteral() ast.Expression {
+	fl := &ast.FunctionLiteral{}
+
+	fl.Parameters = parser.parseFunctionParameters()
+
+	// move from '}' to 'func'
+	parser.nextToken()
+
+	fl.Token = parser.curToken
+
+	if !parser.peekTokenAndNext(lexer.LSQBRAC) {
+		parser.addError(lexer.LSQBRAC)
+		return nil
+	}
+
+	fl.Body = parser.parseFunctionBody()
+
+	return fl
+}
+
+func (parser *Parser) parseFunctionBody() *ast.BlockStatement {
+	// loops around the function do not continue into its body
+	loopDepth := parser.loopDepth
+	parser.loopDepth = 0
+	defer func() { parser.loopDepth = loopDepth }()
+
+	return parser.parseBlockStatement()
+}
+
 func (parser *Parser) parseFunctionParameters() []*ast.Identifier {
 	var identifiers []*ast.Identifier
 
@@ -199,16 +248,17 @@ func (parser *Parser) parseConditionalExpression() ast.Expression {
 }
 
 func (parser *Parser) parseElseIfLadder() *ast.ConditionalExpression {
-	parser.nextToken()
-
-	if parser.curTokenIs(lexer.ELSE) {
+	if parser.peekTokenIs(lexer.ELSE) {
+		parser.nextToken()
 		return parser.parseElseBlock()
 	}
 
-	if !parser.curTokenIs(lexer.LBRACE) {
+	// the next statement may start with '{' too, so only continue on '{...} if else'
+	if !parser.peekTokenIs(lexer.LBRACE) || !parser.isElseIfAhead() {
 		return nil
 	}
 	parser.nextToken()
+	parser.nextToken()
 
 	codExp := &ast.ConditionalExpression{}
 	codExp.Condition = parser.parseExpression(LOWEST, lexer.RBRACE)
@@ -235,6 +285,21 @@ func (parser *Parser) parseElseIfLadder() *ast.ConditionalExpression {
 	return codExp
 }
 
+func (parser *Parser) isElseIfAhead() bool {
+	isElseIf := false
+
+	parser.peekTokenTemp(func() {
+		parser.nextToken()
+		parser.skipToMatching(lexer.LBRACE, lexer.RBRACE)
+
+		isElseIf = parser.peekTokenIs(lexer.IF)
+		parser.nextToken()
+		isElseIf = isElseIf && parser.peekTokenIs(lexer.ELSE)
+	})
+
+	return isElseIf
+}
+
 func (parser *Parser) parseElseBlock() *ast.ConditionalExpression {
 	if !parser.peekTokenAndNext(lexer.LSQBRAC) {
 		return nil
@@ -251,11 +316,30 @@ func (parser *Parser) parseCallExpression() ast.Expression {
 	ce := &ast.CallExpression{}
 	ce.Arguments = parser.parseExpressionList(lexer.RBRACE)
 	parser.nextToken()
-	ce.Function = parser.parseIdentifier().(*ast.Identifier)
 	ce.Token = parser.curToken
+	ce.Function = parser.parseIdentifier()
+
+	// functions of a module are called as {args}module.fn
+	for parser.peekTokenIs(lexer.DOT) && ce.Function != nil {
+		parser.nextToken()
+		ce.Function = parser.parseMemberExpression(ce.Function)
+	}
+
 	return ce
 }
 
+func (parser *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	me := &ast.MemberExpression{Token: parser.curToken, Object: object}
+
+	if !parser.peekTokenAndNext(lexer.IDENT) {
+		parser.addError(lexer.IDENT)
+		return nil
+	}
+	me.Property = &ast.Identifier{Token: parser.curToken, Value: parser.curToken.Literal}
+
+	return me
+}
+
 func (parser *Parser) parseExpressionList(end lexer.TokenType) []ast.Expression {
 	var list []ast.Expression
 
@@ -309,15 +393,93 @@ func (parser *Parser) parseWhileExpression() ast.Expression {
 		return nil
 	}
 
-	we.Body = parser.parseBlockStatement()
+	we.Body = parser.parseLoopBody()
 
 	return we
 }
 
+func (parser *Parser) parseTryExpression() ast.Expression {
+	te := &ast.TryExpression{Token: parser.curToken}
+
+	if !parser.peekTokenAndNext(lexer.LSQBRAC) {
+		return nil
+	}
+
+	te.Body = parser.parseBlockStatement()
+
+	if !parser.peekTokenAndNext(lexer.LBRACE) {
+		return nil
+	}
+
+	if parser.peekTokenIs(lexer.IDENT) {
+		parser.nextToken()
+		te.Variable = &ast.Identifier{Token: parser.curToken, Value: parser.curToken.Literal}
+	}
+
+	if !parser.peekTokenAndNext(lexer.RBRACE) || !parser.peekTokenAndNext(lexer.CATCH) || !parser.peekTokenAndNext(lexer.LSQBRAC) {
+		return nil
+	}
+
+	te.Handler = parser.parseBlockStatement()
+
+	return te
+}
+
+func (parser *Parser) parseForExpression() ast.Expression {
+	fe := &ast.ForExpression{}
+
+	if !parser.peekTokenAndNext(lexer.IDENT) {
+		return nil
+	}
+
+	fe.Variable = &ast.Identifier{Token: parser.curToken, Value: parser.curToken.Literal}
+
+	if !parser.peekTokenAndNext(lexer.SEMICOLON) {
+		return nil
+	}
+
+	args := parser.parseExpressionList(lexer.RBRACE)
+
+	switch len(args) {
+	case 1:
+		fe.Iterable = args[0]
+	case 2, 3:
+		fe.Start = args[0]
+		fe.End = args[1]
+		if len(args) == 3 {
+			fe.Step = args[2]
+		}
+	default:
+		parser.addGenericError("for expects an iterable or a range of start; end; step")
+		return nil
+	}
+
+	if !parser.peekTokenAndNext(lexer.FOR) {
+		return nil
+	}
+
+	fe.Token = parser.curToken
+
+	if !parser.peekTokenAndNext(lexer.LSQBRAC) {
+		return nil
+	}
+
+	fe.Body = parser.parseLoopBody()
+
+	return fe
+}
+
 func (parser *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: parser.curToken, Value: parser.curToken.Literal}
 }
 
+// parseErrorToken reports a literal the lexer could not read, the token
+// carries the message
+func (parser *Parser) parseErrorToken() ast.Expression {
+	parser.addGenericError(parser.curToken.Literal)
+	return nil
+}
+
 func (parser *Parser) parseLParenExpression() ast.Expression {
 	isIndexExp := false
 
@@ -326,7 +488,7 @@ func (parser *Parser) parseLParenExpression() ast.Expression {
 			parser.nextToken()
 		}
 
-		isIndexExp = parser.peekTokenIs(lexer.IDENT) || parser.peekTokenIs(lexer.LPAREN) || parser.peekTokenIs(lexer.LSQBRAC)
+		isIndexExp = parser.isPeekTokenAny(lexer.IDENT, lexer.LPAREN, lexer.LSQBRAC, lexer.STRING, lexer.RAW_STRING)
 	})
 
 	if isIndexExp {
@@ -357,9 +519,12 @@ func (parser *Parser) parseIndexExpression() ast.Expression {
 	if parser.curTokenIs(lexer.IDENT) {
 		ie.Array = parser.parseIdentifier()
 	} else if parser.curTokenIs(lexer.LPAREN) {
-		ie.Array = parser.parseArrayLiteral()
+		// either an array literal or another index, as in (1)(2)grid
+		ie.Array = parser.parseLParenExpression()
 	} else if parser.curTokenIs(lexer.LSQBRAC) {
 		ie.Array = parser.parseHashLiteral()
+	} else if parser.isCurTokenAny(lexer.STRING, lexer.RAW_STRING) {
+		ie.Array = parser.parseStringLiteral()
 	}
 
 	return ie
@@ -402,8 +567,22 @@ func (parser *Parser) parseAssignExpression(value ast.Expression) ast.Expression
 	ae.Operator = parser.curToken.Literal
 
 	parser.nextToken()
-	ae.Name = parser.parseIdentifier().(*ast.Identifier)
 	ae.Token = parser.curToken
 
+	switch parser.curToken.Type {
+	case lexer.IDENT:
+		ae.Target = parser.parseIdentifier()
+	case lexer.LPAREN:
+		target, ok := parser.parseLParenExpression().(*ast.IndexExpression)
+		if !ok {
+			parser.addDiagnostic(Diagnostic{Pos: ae.Token.Pos, Message: "invalid assignment target", Got: ae.Token.Type})
+			return nil
+		}
+		ae.Target = target
+	default:
+		parser.addGenericError("invalid assignment target")
+		return nil
+	}
+
 	return ae
 }
@@ -2,9 +2,12 @@ package parser_test
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/parser"
 	"github.com/SirusCodes/anti-lang/src/utils"
 )
 
@@ -105,6 +108,15 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"a + {b; c}add + d", "((a + ({b;c}add)) + d)"},
 		{"a % b == c % d", "((a % b) == (c % d))"},
 		{"a <= b", "(a <= b)"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"a ** -b", "(a ** (-b))"},
+		{"a // b * c", "((a // b) * c)"},
+		{"a + b // c", "(a + (b // c))"},
+		{"a ?? b || c && d", "(((a ?? b) || c) && d)"},
+		{"a == b ?? c", "((a == b) ?? c)"},
+		{"a || b += c", "(a || b) += c"},
 	}
 	for _, tt := range tests {
 		program := utils.ParseInput(t, tt.input)
@@ -330,6 +342,29 @@ func TestFunctionExpressionParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.ReturnValue, "x", "+", "y")
 }
 
+func TestFunctionLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",{x} func [ ,x * 2 return ] = double let", ",{x}func[,(x * 2) return] = double let"},
+		{",{arr; {x} func [ ,x * 2 return ]}map", "({arr;{x}func[,(x * 2) return]}map)"},
+		{",({} func [ ,1 return ]; {a; b} func [ a + b ])", "({}func[,1 return]; {a; b}func[(a + b)])"},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestWhileExpressionParsing(t *testing.T) {
 	input := "{x < y} while [ ,b return ]"
 
@@ -368,6 +403,166 @@ func TestWhileExpressionParsing(t *testing.T) {
 	testIdentifier(t, bodyStmt.ReturnValue, "b")
 }
 
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		variable string
+	}{
+		{"try [ ,{x}f ] {err} catch [ ,err.message ]", "err"},
+		{"try [ ,{x}f ] {} catch [ ,0 ]", ""},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		tryExp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+		}
+
+		if len(tryExp.Body.Statements) != 1 || len(tryExp.Handler.Statements) != 1 {
+			t.Fatalf("wrong number of statements. body=%d, handler=%d", len(tryExp.Body.Statements), len(tryExp.Handler.Statements))
+		}
+
+		if tt.variable == "" {
+			if tryExp.Variable != nil {
+				t.Errorf("tryExp.Variable is not nil. got=%s", tryExp.Variable)
+			}
+			continue
+		}
+
+		testIdentifier(t, tryExp.Variable, tt.variable)
+	}
+}
+
+func TestBlockValueInLetStatement(t *testing.T) {
+	// statements inside the blocks must not run into the outer 'let'
+	tests := []string{
+		",{c} if [ ,{1}f ] else [ ,2 ] = x let",
+		",try [ ,{1}f ] {e} catch [ ,2 ] = x let",
+	}
+
+	for _, input := range tests {
+		program := utils.ParseInput(t, input)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		if _, ok := program.Statements[0].(*ast.LetStatement); !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+		}
+	}
+}
+
+func TestForExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		variable string
+		iterable string
+		start    interface{}
+		end      interface{}
+		step     interface{}
+	}{
+		{"{x; arr} for [ ,{x}print ]", "x", "arr", nil, nil, nil},
+		{"{i; 1; 10} for [ ,{i}print ]", "i", "", 1, 10, nil},
+		{"{i; 10; 1; -2} for [ ,{i}print ]", "i", "", 10, 1, "(-2)"},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		forExp, ok := stmt.Expression.(*ast.ForExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.ForExpression. got=%T", stmt.Expression)
+		}
+
+		if !testIdentifier(t, forExp.Variable, tt.variable) {
+			return
+		}
+
+		if tt.iterable != "" {
+			if !testIdentifier(t, forExp.Iterable, tt.iterable) {
+				return
+			}
+		} else {
+			testLiteralExpression(t, forExp.Start, tt.start)
+			testLiteralExpression(t, forExp.End, tt.end)
+		}
+
+		if tt.step != nil && forExp.Step.String() != tt.step {
+			t.Errorf("forExp.Step is not %q. got=%q", tt.step, forExp.Step.String())
+		}
+
+		if len(forExp.Body.Statements) != 1 {
+			t.Fatalf("forExp.Body.Statements has not 1 statements. got=%d\n", len(forExp.Body.Statements))
+		}
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"99999999999999999999", "99999999999999999999"},
+		{"1.50d", "1.50"},
+		{"7d", "7"},
+		{"0xFF", "255"},
+		{"0o17", "15"},
+		{"0b1010", "10"},
+		{"1_000_000", "1000000"},
+		{"0xFFFF_FFFF_FFFF_FFFF", "18446744073709551615"},
+		{"1_000.000_1d", "1000.0001"},
+		{"6.02e23", "6.02e+23"},
+		{"1_5e-2", "0.15"},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		switch literal := stmt.Expression.(type) {
+		case *ast.IntegerLiteral:
+			if got := strconv.FormatInt(literal.Value, 10); got != tt.expected {
+				t.Errorf("literal.Value not %q. got=%q", tt.expected, got)
+			}
+		case *ast.FloatLiteral:
+			if got := strconv.FormatFloat(literal.Value, 'g', -1, 64); got != tt.expected {
+				t.Errorf("literal.Value not %q. got=%q", tt.expected, got)
+			}
+		case *ast.BigIntLiteral:
+			if literal.Value.String() != tt.expected {
+				t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value.String())
+			}
+		case *ast.DecimalLiteral:
+			if literal.Value != tt.expected {
+				t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+			}
+		default:
+			t.Errorf("%q: unexpected literal %T", tt.input, stmt.Expression)
+		}
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `$hello world$`
 
@@ -469,6 +664,33 @@ func TestIndexOnArrayLiteral(t *testing.T) {
 	}
 }
 
+func TestIndexOnStringLiteral(t *testing.T) {
+	input := "(2)$abc$"
+
+	program := utils.ParseInput(t, input)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	str, ok := indexExp.Array.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("indexExp.Array is not ast.StringLiteral. got=%T", indexExp.Array)
+	}
+
+	if str.Value != "abc" {
+		t.Fatalf("str.Value not %q. got=%q", "abc", str.Value)
+	}
+
+	testIntegerLiteral(t, indexExp.Index, 2)
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := "[$one$ = 1; $two$ = 2; $three$ = 3]"
 
@@ -634,6 +856,31 @@ func TestParsingCommaCallExpression(t *testing.T) {
 	testIntegerLiteral(t, callExpression.Arguments[1], 2)
 }
 
+func TestMemberExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",utils.ten", "utils.ten"},
+		{",utils.ten + 1", "(utils.ten + 1)"},
+		{",{1; 2}utils.add", "({1;2}utils.add)"},
+		{",{x}a.b.c", "({x}a.b.c)"},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
 func TestAssignExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -664,8 +911,48 @@ func TestAssignExpression(t *testing.T) {
 			t.Errorf("stmt.Operator is not '%s'. got=%s", tt.operator, stmt.Operator)
 		}
 
-		if stmt.Name.Value != tt.name {
-			t.Errorf("stmt.Name.Value is not '%s'. got=%s", tt.name, stmt.Name.Value)
+		if !testIdentifier(t, stmt.Target, tt.name) {
+			return
+		}
+	}
+}
+
+func TestIndexAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",5 = (2)arr", "5 = {arr(2)}"},
+		{",1 += ($k$)map", "1 += {map(k)}"},
+		{",0 = (1)(2)grid", "0 = {{grid(2)}(1)}"},
+		{",(1)(2)grid", "{{grid(2)}(1)}"},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",5 = 3", "1:6: invalid assignment target"},
+		{",5 = (1; 2)", "1:6: invalid assignment target"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
 		}
 	}
 }
@@ -7,28 +7,26 @@ import (
 	"github.com/SirusCodes/anti-lang/src/lexer"
 )
 
-var (
-	tempCurToken  lexer.Token
-	tempPeekToken lexer.Token
-)
-
 const (
 	_ int = iota
 	LOWEST
-	COND        // && ||
 	ASSIGN      // = += -= *= /=
+	COND        // && || ??
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // + or -
-	PRODUCT     // * or /
+	PRODUCT     // * / or //
 	MOD         // %
 	PREFIX      // -X or !X
+	POWER       // **, binds tighter than a prefix so -2 ** 2 is -(2 ** 2)
 	CALL        // myFunction(X)
+	MEMBER      // module.member
 )
 
 var precedences = map[lexer.TokenType]int{
 	lexer.LOG_AND:  COND,
 	lexer.LOG_OR:   COND,
+	lexer.NULLISH:  COND,
 	lexer.ASSIGN:   ASSIGN,
 	lexer.ASTER_EQ: ASSIGN,
 	lexer.MINUS_EQ: ASSIGN,
@@ -44,8 +42,11 @@ var precedences = map[lexer.TokenType]int{
 	lexer.MINUS:    SUM,
 	lexer.SLASH:    PRODUCT,
 	lexer.ASTERISK: PRODUCT,
+	lexer.INT_DIV:  PRODUCT,
 	lexer.MOD:      MOD,
+	lexer.POWER:    POWER,
 	lexer.LBRACE:   CALL,
+	lexer.DOT:      MEMBER,
 }
 
 // Defination of parser functions
@@ -60,14 +61,39 @@ type Parser struct {
 	curToken  lexer.Token
 	peekToken lexer.Token
 
+	// tokens keeps what the lexer returned while a lookahead is running so
+	// the parser can go back to it, next is the index of the token after
+	// peekToken. marks counts the lookaheads in progress.
+	tokens []lexer.Token
+	next   int
+	marks  int
+
 	infixParseFns  infixParseFns
 	prefixParseFns prefixParseFns
 
-	errors []string
+	diagnostics []Diagnostic
+	// panicking is set after an error until the parser synchronizes at the
+	// next statement boundary, so a single mistake is only reported once
+	panicking bool
+
+	// number of loops around the current statement within the current function
+	loopDepth int
+}
+
+// Diagnostic is a single problem found while parsing
+type Diagnostic struct {
+	Pos      lexer.Position
+	Message  string
+	Expected lexer.TokenType // empty when no particular token was expected
+	Got      lexer.TokenType
+}
+
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
 }
 
 func New(l *lexer.Lexer) *Parser {
-	parser := &Parser{lexer: l, errors: []string{}}
+	parser := &Parser{lexer: l, diagnostics: []Diagnostic{}}
 
 	// To set both curToken and peekToken
 	parser.nextToken()
@@ -78,14 +104,18 @@ func New(l *lexer.Lexer) *Parser {
 	parser.registerPrefix(lexer.IDENT, parser.parseIdentifier)
 	parser.registerPrefix(lexer.INT, parser.parseIntegerLiteral)
 	parser.registerPrefix(lexer.FLOAT, parser.parseFloatLiteral)
+	parser.registerPrefix(lexer.DECIMAL, parser.parseDecimalLiteral)
 	parser.registerPrefix(lexer.BANG, parser.parsePrefixExpression)
 	parser.registerPrefix(lexer.MINUS, parser.parsePrefixExpression)
 	parser.registerPrefix(lexer.TRUE, parser.parseBoolean)
 	parser.registerPrefix(lexer.FALSE, parser.parseBoolean)
 	parser.registerPrefix(lexer.LBRACE, parser.parseLBraceExpression)
 	parser.registerPrefix(lexer.STRING, parser.parseStringLiteral)
+	parser.registerPrefix(lexer.RAW_STRING, parser.parseStringLiteral)
+	parser.registerPrefix(lexer.ERROR, parser.parseErrorToken)
 	parser.registerPrefix(lexer.LPAREN, parser.parseLParenExpression)
 	parser.registerPrefix(lexer.LSQBRAC, parser.parseHashLiteral)
+	parser.registerPrefix(lexer.TRY, parser.parseTryExpression)
 
 	// All infix parse functions
 	parser.infixParseFns = make(infixParseFns)
@@ -101,19 +131,53 @@ func New(l *lexer.Lexer) *Parser {
 	parser.registerInfix(lexer.MINUS, parser.parseInfixExpression)
 	parser.registerInfix(lexer.MINUS_EQ, parser.parseAssignExpression)
 	parser.registerInfix(lexer.MOD, parser.parseInfixExpression)
+	parser.registerInfix(lexer.POWER, parser.parseInfixExpression)
+	parser.registerInfix(lexer.INT_DIV, parser.parseInfixExpression)
 	parser.registerInfix(lexer.NOT_EQ, parser.parseInfixExpression)
 	parser.registerInfix(lexer.LOG_OR, parser.parseInfixExpression)
+	parser.registerInfix(lexer.NULLISH, parser.parseInfixExpression)
 	parser.registerInfix(lexer.PLUS, parser.parseInfixExpression)
 	parser.registerInfix(lexer.PLUS_EQ, parser.parseAssignExpression)
 	parser.registerInfix(lexer.SLASH, parser.parseInfixExpression)
 	parser.registerInfix(lexer.SLASH_EQ, parser.parseAssignExpression)
+	parser.registerInfix(lexer.DOT, parser.parseMemberExpression)
 
 	return parser
 }
 
 func (parser *Parser) nextToken() {
 	parser.curToken = parser.peekToken
-	parser.peekToken = parser.lexer.NextToken()
+
+	switch {
+	case parser.next < len(parser.tokens):
+		parser.peekToken = parser.tokens[parser.next]
+		parser.next++
+	case parser.marks > 0:
+		parser.peekToken = parser.lexer.NextToken()
+		parser.tokens = append(parser.tokens, parser.peekToken)
+		parser.next++
+	default:
+		// nobody can come back here anymore
+		parser.tokens, parser.next = parser.tokens[:0], 0
+		parser.peekToken = parser.lexer.NextToken()
+	}
+}
+
+// mark is a place in the token stream the parser can go back to
+type mark struct {
+	curToken  lexer.Token
+	peekToken lexer.Token
+	next      int
+}
+
+func (parser *Parser) mark() mark {
+	parser.marks++
+	return mark{curToken: parser.curToken, peekToken: parser.peekToken, next: parser.next}
+}
+
+func (parser *Parser) reset(m mark) {
+	parser.marks--
+	parser.curToken, parser.peekToken, parser.next = m.curToken, m.peekToken, m.next
 }
 
 func (parser *Parser) registerPrefix(tokenType lexer.TokenType, fn func() ast.Expression) {
@@ -129,25 +193,91 @@ func (parser *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !parser.curTokenIs(lexer.EOF) {
-		stmt := parser.parseStatement()
-		program.Statements = append(program.Statements, stmt)
-		parser.nextToken()
+		if stmt, ok := parser.parseRecoverableStatement(); ok {
+			program.Statements = append(program.Statements, stmt)
+		}
 	}
 
 	return program
 }
 
+// parseRecoverableStatement parses a statement and moves past it. Statements
+// with errors are dropped so the evaluator never sees half parsed nodes.
+func (parser *Parser) parseRecoverableStatement() (ast.Statement, bool) {
+	errorCount := len(parser.diagnostics)
+	start := parser.curToken.Pos
+	stmt := parser.parseStatement()
+
+	if parser.panicking {
+		parser.synchronize(start)
+		return nil, false
+	}
+
+	parser.nextToken()
+
+	return stmt, len(parser.diagnostics) == errorCount
+}
+
+// synchronize skips tokens until the end of the broken statement that began
+// at start, which is right after a 'let', 'return' or 'import', or just
+// before a ',' or the closing ']'
+func (parser *Parser) synchronize(start lexer.Position) {
+	parser.panicking = false
+
+	for !parser.curTokenIs(lexer.EOF) {
+		// the broken statement already ran into the next one or the end of
+		// its block, which is left for the block to close
+		if parser.isCurTokenAny(lexer.COMMA, lexer.RSQBRAC) && parser.curToken.Pos != start {
+			return
+		}
+
+		if parser.isCurTokenAny(lexer.LET, lexer.RETURN, lexer.IMPORT) {
+			parser.nextToken()
+			return
+		}
+
+		if parser.peekTokenIs(lexer.COMMA) || parser.peekTokenIs(lexer.RSQBRAC) {
+			parser.nextToken()
+			return
+		}
+
+		parser.nextToken()
+	}
+}
+
 func (parser *Parser) Errors() []string {
-	return parser.errors
+	errors := make([]string, len(parser.diagnostics))
+	for i, d := range parser.diagnostics {
+		errors[i] = d.String()
+	}
+	return errors
+}
+
+func (parser *Parser) Diagnostics() []Diagnostic {
+	return parser.diagnostics
+}
+
+func (parser *Parser) addDiagnostic(d Diagnostic) {
+	if parser.panicking {
+		return
+	}
+
+	parser.panicking = true
+	parser.diagnostics = append(parser.diagnostics, d)
 }
 
 func (parser *Parser) addGenericError(message string) {
-	parser.errors = append(parser.errors, message)
+	parser.addDiagnostic(Diagnostic{Pos: parser.curToken.Pos, Message: message, Got: parser.curToken.Type})
 }
 
 func (parser *Parser) addError(t lexer.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, parser.peekToken.Type)
-	parser.errors = append(parser.errors, msg)
+	parser.addDiagnostic(Diagnostic{Pos: parser.peekToken.Pos, Message: msg, Expected: t, Got: parser.peekToken.Type})
+}
+
+func (parser *Parser) addCurTokenError(t lexer.TokenType) {
+	msg := fmt.Sprintf("expected token to be %s, got %s instead", t, parser.curToken.Type)
+	parser.addDiagnostic(Diagnostic{Pos: parser.curToken.Pos, Message: msg, Expected: t, Got: parser.curToken.Type})
 }
 
 func (parser *Parser) curTokenIs(t lexer.TokenType) bool {
@@ -158,14 +288,13 @@ func (parser *Parser) peekTokenIs(t lexer.TokenType) bool {
 	return parser.peekToken.Type == t
 }
 
+// peekTokenTemp runs fn, which may move through the tokens ahead, and then
+// goes back to the current token. Lookaheads can nest.
 func (parser *Parser) peekTokenTemp(fn func()) {
-	tempCurToken = parser.curToken
-	tempPeekToken = parser.peekToken
-
-	parser.lexer.MoveReaderForTemp(fn)
+	m := parser.mark()
+	defer parser.reset(m)
 
-	parser.curToken = tempCurToken
-	parser.peekToken = tempPeekToken
+	fn()
 }
 
 func (parser *Parser) peekTokenAndNext(t lexer.TokenType) bool {
@@ -183,11 +312,30 @@ func (parser *Parser) curTokenIsAndNext(t lexer.TokenType) bool {
 		parser.nextToken()
 		return true
 	} else {
-		parser.addError(t)
+		parser.addCurTokenError(t)
 		return false
 	}
 }
 
+// skipToMatching moves to the close token matching the open token under the
+// cursor, skipping over nested pairs
+func (parser *Parser) skipToMatching(open, close lexer.TokenType) {
+	depth := 0
+
+	for !parser.curTokenIs(lexer.EOF) {
+		if parser.curTokenIs(open) {
+			depth++
+		} else if parser.curTokenIs(close) {
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+
+		parser.nextToken()
+	}
+}
+
 func (parser *Parser) parseStatement() ast.Statement {
 	switch parser.curToken.Type {
 	case lexer.COMMA:
@@ -203,20 +351,22 @@ func (parser *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: parser.curToken, Value: parser.curToken.Literal}
 }
 
-func (parser *Parser) peekPrecedence() int {
-	if p, ok := precedences[parser.peekToken.Type]; ok {
+// Precedence is how tightly an infix operator binds, LOWEST for tokens that
+// are not operators
+func Precedence(t lexer.TokenType) int {
+	if p, ok := precedences[t]; ok {
 		return p
 	}
 
 	return LOWEST
 }
 
-func (parser *Parser) curPrecedence() int {
-	if p, ok := precedences[parser.curToken.Type]; ok {
-		return p
-	}
+func (parser *Parser) peekPrecedence() int {
+	return Precedence(parser.peekToken.Type)
+}
 
-	return LOWEST
+func (parser *Parser) curPrecedence() int {
+	return Precedence(parser.curToken.Type)
 }
 
 func (parser *Parser) isCurTokenAny(t ...lexer.TokenType) bool {
@@ -228,3 +378,13 @@ func (parser *Parser) isCurTokenAny(t ...lexer.TokenType) bool {
 
 	return false
 }
+
+func (parser *Parser) isPeekTokenAny(t ...lexer.TokenType) bool {
+	for _, token := range t {
+		if parser.peekTokenIs(token) {
+			return true
+		}
+	}
+
+	return false
+}
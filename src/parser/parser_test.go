@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/parser"
+)
+
+// TestConcurrentParsing parses the same programs from many goroutines at once,
+// run it with -race. Every parser has to look ahead on its own tokens.
+func TestConcurrentParsing(t *testing.T) {
+	inputs := []string{
+		",{1; {2; 3}add}add = x let ,{x} if [ ,(1)(2)grid ] else [ ,(1; 2) ]",
+		"{a; b} add func [ ,{a + b} * 2 return ] ,{(2)(1)grid; {3}add}add",
+		",$lib.al$ = lib import ,{1}lib.f ,5 = (1)arr ,[$a$ = {x} func [ ,x ]]",
+		"{x; (1; 2; 3)} for [ ,{{x} func [ ,{x}print ]}map ] try [ ,{$e$}raise ] {e} catch []",
+	}
+
+	paths, _ := filepath.Glob("../../sample/*.al")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(src))
+	}
+
+	expected := make([]string, len(inputs))
+	for i, input := range inputs {
+		p := parser.New(lexer.New(input))
+		expected[i] = p.ParseProgram().String()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%q: unexpected errors %q", input, p.Errors())
+		}
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for round := 0; round < 20; round++ {
+				for i, input := range inputs {
+					p := parser.New(lexer.New(input))
+					if got := p.ParseProgram().String(); got != expected[i] || len(p.Errors()) != 0 {
+						t.Errorf("%q: parsed differently in parallel, got=%q (%q)", input, got, p.Errors())
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -16,11 +16,25 @@ func (parser *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (parser *Parser) parseStatementByComma() ast.Statement {
 	parser.nextToken()
 
+	switch parser.curToken.Type {
+	case lexer.BREAK, lexer.CONTINUE:
+		return parser.parseLoopControlStatement()
+	}
+
 	var token lexer.Token
 	isAssign := false
 	parser.peekTokenTemp(func() {
-		for !parser.curTokenIs(lexer.LET) && !parser.curTokenIs(lexer.RETURN) && !parser.curTokenIs(lexer.EOF) && !parser.curTokenIs(lexer.COMMA) {
+		// an unmatched ']' closes the block the statement is in
+		for !parser.isCurTokenAny(lexer.LET, lexer.RETURN, lexer.IMPORT, lexer.EOF, lexer.COMMA, lexer.RSQBRAC) {
 			isAssign = parser.isCurTokenAny(lexer.ASSIGN, lexer.ASTER_EQ, lexer.PLUS_EQ, lexer.MINUS_EQ, lexer.SLASH_EQ) || isAssign
+
+			// blocks of nested functions have statements of their own
+			switch parser.curToken.Type {
+			case lexer.LBRACE:
+				parser.skipToMatching(lexer.LBRACE, lexer.RBRACE)
+			case lexer.LSQBRAC:
+				parser.skipToMatching(lexer.LSQBRAC, lexer.RSQBRAC)
+			}
 			parser.nextToken()
 		}
 
@@ -32,6 +46,8 @@ func (parser *Parser) parseStatementByComma() ast.Statement {
 		return parser.parseLetStatement()
 	case lexer.RETURN:
 		return parser.parseReturnStatement()
+	case lexer.IMPORT:
+		return parser.parseImportStatement()
 	default:
 		return parser.parseExpressionStatement()
 	}
@@ -47,7 +63,7 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 	parser.nextToken()
 
 	if !parser.curTokenIs(lexer.IDENT) {
-		parser.addGenericError("parser: expected token to be IDENT, got " + parser.curToken.Literal + " instead")
+		parser.addCurTokenError(lexer.IDENT)
 		return nil
 	}
 	letStatement.Name = &ast.Identifier{Token: parser.curToken, Value: parser.curToken.Literal}
@@ -71,16 +87,75 @@ func (parser *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return returnStatement
 }
 
+// parseImportStatement parses `,$path$ import` and `,$path$ = name import`
+func (parser *Parser) parseImportStatement() ast.Statement {
+	is := &ast.ImportStatement{}
+
+	if parser.curTokenIs(lexer.ERROR) {
+		parser.parseErrorToken()
+		return nil
+	}
+
+	if !parser.isCurTokenAny(lexer.STRING, lexer.RAW_STRING) {
+		parser.addCurTokenError(lexer.STRING)
+		return nil
+	}
+	is.Path = &ast.StringLiteral{Token: parser.curToken, Value: parser.curToken.Literal}
+
+	if parser.peekTokenIs(lexer.ASSIGN) {
+		parser.nextToken()
+
+		if !parser.peekTokenAndNext(lexer.IDENT) {
+			parser.addError(lexer.IDENT)
+			return nil
+		}
+		is.Name = &ast.Identifier{Token: parser.curToken, Value: parser.curToken.Literal}
+	}
+
+	if !parser.peekTokenAndNext(lexer.IMPORT) {
+		parser.addError(lexer.IMPORT)
+		return nil
+	}
+	is.Token = parser.curToken
+
+	return is
+}
+
+func (parser *Parser) parseLoopControlStatement() ast.Statement {
+	if parser.loopDepth == 0 {
+		parser.addGenericError(parser.curToken.Literal + " outside of loop")
+		return nil
+	}
+
+	if parser.curTokenIs(lexer.BREAK) {
+		return &ast.BreakStatement{Token: parser.curToken}
+	}
+	return &ast.ContinueStatement{Token: parser.curToken}
+}
+
+// parseLoopBody parses the block of a loop, where break and continue are allowed
+func (parser *Parser) parseLoopBody() *ast.BlockStatement {
+	parser.loopDepth++
+	defer func() { parser.loopDepth-- }()
+
+	return parser.parseBlockStatement()
+}
+
 func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: parser.curToken}
 
 	parser.nextToken()
 
 	for !parser.curTokenIs(lexer.RSQBRAC) && !parser.curTokenIs(lexer.EOF) {
-		stmt := parser.parseStatement()
-		block.Statements = append(block.Statements, stmt)
-		parser.nextToken()
+		if stmt, ok := parser.parseRecoverableStatement(); ok {
+			block.Statements = append(block.Statements, stmt)
+		}
+	}
+
+	if !parser.curTokenIs(lexer.RSQBRAC) {
+		parser.addCurTokenError(lexer.RSQBRAC)
 	}
+	block.Close = parser.curToken
 
 	return block
 }
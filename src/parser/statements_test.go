@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/parser"
 	"github.com/SirusCodes/anti-lang/src/utils"
 )
 
@@ -125,3 +127,261 @@ func TestMultiStatementInAFunction(t *testing.T) {
 		t.Fatalf("stmt3.ReturnValue.String() not 'five'. got=%q", stmt4.ReturnValue.String())
 	}
 }
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",(1; 2 = a let", "1:12: expected next token to be ), got LET instead"},
+		{"\n\n  ,5 = 6 let", "3:8: expected token to be IDENT, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParserRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements int
+	}{
+		{
+			",5 = let\n,{1}print\n,(1; 2 = a let\n,3 = b let",
+			[]string{
+				"1:6: expected token to be IDENT, got LET instead",
+				"3:12: expected next token to be ), got LET instead",
+			},
+			2,
+		},
+		{
+			"{} main func [\n\t,5 = let\n\t,{1}print\n]\n,{}main",
+			[]string{"2:7: expected token to be IDENT, got LET instead"},
+			1,
+		},
+		{
+			"{} main func [\n\t,{1}print\n",
+			[]string{"3:1: expected token to be ], got EOF instead"},
+			0,
+		},
+		{
+			"] ,1 = a let",
+			[]string{"1:1: no prefix parse function for ]"},
+			1,
+		},
+		{
+			",$a \\q$ import\n,$$$raw$$$ = b let\n,$never closed = a let",
+			[]string{"1:5: unknown escape sequence \\q", "3:2: unterminated string"},
+			1,
+		},
+		{
+			",0x = a let\n,1.2.3 + 0b12 = b let\n,7 = c let",
+			[]string{"1:2: number 0x has no digits", "2:2: 1.2.3 has more than one decimal point"},
+			1,
+		},
+		{
+			"{x} if [ ,1 + ] ,{2}print",
+			[]string{"1:15: no prefix parse function for ]"},
+			1,
+		},
+		{
+			"{a; b} f func [ ,a + return ]",
+			[]string{"1:22: no prefix parse function for RETURN"},
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%d, got=%d (%q)", tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i] != expected {
+				t.Errorf("wrong error. expected=%q, got=%q", expected, errors[i])
+			}
+		}
+
+		if len(program.Statements) != tt.expectedStatements {
+			t.Errorf("wrong number of statements for %q. expected=%d, got=%d", tt.input, tt.expectedStatements, len(program.Statements))
+		}
+
+		for _, stmt := range program.Statements {
+			if stmt == nil {
+				t.Errorf("program contains nil statement for %q", tt.input)
+			}
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	p := parser.New(lexer.New(",(1; 2 = a let"))
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got=%d", len(diagnostics))
+	}
+
+	d := diagnostics[0]
+	if d.Expected != lexer.RPAREN || d.Got != lexer.LET {
+		t.Errorf("wrong expected/got tokens. got expected=%q, got=%q", d.Expected, d.Got)
+	}
+
+	if d.Pos.Line != 1 || d.Pos.Column != 12 {
+		t.Errorf("wrong position. got=%s", d.Pos)
+	}
+}
+
+func TestIfWithoutElseFollowedByStatement(t *testing.T) {
+	input := `{x} f func [
+		{x < 2} if [ ,1 return ]
+		,2 return
+	]`
+
+	program := utils.ParseInput(t, input)
+
+	fn, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionExpression. got=%T", program.Statements[0])
+	}
+
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("function.Body.Statements does not contain 2 statements. got=%d", len(fn.Body.Statements))
+	}
+
+	if _, ok := fn.Body.Statements[1].(*ast.ReturnStatement); !ok {
+		t.Fatalf("second statement is not ast.ReturnStatement. got=%T", fn.Body.Statements[1])
+	}
+}
+
+func TestLoopControlStatements(t *testing.T) {
+	input := `{x; arr} for [
+		{x > 2} if [ ,break ]
+		,continue
+	]`
+
+	program := utils.ParseInput(t, input)
+
+	loop, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ForExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ForExpression. got=%T", program.Statements[0])
+	}
+
+	if len(loop.Body.Statements) != 2 {
+		t.Fatalf("loop.Body.Statements does not contain 2 statements. got=%d", len(loop.Body.Statements))
+	}
+
+	cond := loop.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ConditionalExpression)
+	if _, ok := cond.ExecutionBlock.Statements[0].(*ast.BreakStatement); !ok {
+		t.Errorf("statement is not ast.BreakStatement. got=%T", cond.ExecutionBlock.Statements[0])
+	}
+
+	if _, ok := loop.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("statement is not ast.ContinueStatement. got=%T", loop.Body.Statements[1])
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",break", "1:2: break outside of loop"},
+		{"{x} if [\n\t,continue\n]", "2:3: continue outside of loop"},
+		{"{true} while [ {} f func [ ,break ] ]", "1:29: break outside of loop"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("expected 1 error for %q, got=%d (%q)", tt.input, len(errors), errors)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	tests := []struct {
+		input       string
+		path        string
+		bindingName string
+	}{
+		{",$utils.al$ import", "utils.al", "utils"},
+		{",$lib/math.al$ = m import", "lib/math.al", "m"},
+	}
+
+	for _, tt := range tests {
+		program := utils.ParseInput(t, tt.input)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt is not ast.ImportStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Path.Value != tt.path {
+			t.Errorf("stmt.Path.Value not %q. got=%q", tt.path, stmt.Path.Value)
+		}
+
+		if stmt.BindingName() != tt.bindingName {
+			t.Errorf("stmt.BindingName() not %q. got=%q", tt.bindingName, stmt.BindingName())
+		}
+
+		if stmt.String() != tt.input {
+			t.Errorf("stmt.String() not %q. got=%q", tt.input, stmt.String())
+		}
+	}
+}
+
+func TestInvalidImportStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{",$a.al$ = import", "1:11: expected next token to be IDENT, got IMPORT instead"},
+		{",$a.al$ = (1)x import", "1:11: expected next token to be IDENT, got ( instead"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected an error for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
-	"github.com/SirusCodes/anti-lang/src/evaluator"
-	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/antilang"
 	"github.com/SirusCodes/anti-lang/src/object"
 	"github.com/SirusCodes/anti-lang/src/parser"
 )
@@ -14,38 +14,54 @@ import (
 const prompt = ">> "
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	// input reads from the same reader, so it sees the lines after its call
+	reader := bufio.NewReader(in)
+	interpreter := antilang.New()
+	interpreter.SetStdin(reader)
+	interpreter.SetStdout(out)
 
 	for {
-		fmt.Print(prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		fmt.Fprint(out, prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
+		line = strings.TrimRight(line, "\r\n")
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.ParseProgram()
+		result, err := interpreter.Run(line)
 
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		switch err := err.(type) {
+		case nil:
+		case *antilang.SyntaxError:
+			printParserErrors(out, err.Diagnostics)
+			continue
+		case *antilang.Error:
+			printRuntimeError(out, err.Err, line)
+			continue
+		default:
+			io.WriteString(out, err.Error()+"\n")
 			continue
 		}
 
-		evaluator := evaluator.Eval(program, env)
-
-		if evaluator != nil {
-			io.WriteString(out, evaluator.Inspect())
+		if result != nil {
+			io.WriteString(out, result.Inspect())
 			io.WriteString(out, "\n")
 		}
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, diagnostics []parser.Diagnostic) {
 	io.WriteString(out, "Guess you are not ready for it...\nLet me help you with that with not so useful errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, d := range diagnostics {
+		io.WriteString(out, "\t"+d.String()+"\n")
+	}
+}
+
+func printRuntimeError(out io.Writer, err *object.Error, line string) {
+	// functions defined on earlier lines report positions in those lines,
+	// which are gone by now, so only underline errors raised at the top level
+	if len(err.Stack) > 0 {
+		line = ""
 	}
+	io.WriteString(out, err.Traceback("", line))
 }
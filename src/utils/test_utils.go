@@ -4,20 +4,54 @@ import (
 	"testing"
 
 	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/compiler"
 	"github.com/SirusCodes/anti-lang/src/evaluator"
 	"github.com/SirusCodes/anti-lang/src/lexer"
 	"github.com/SirusCodes/anti-lang/src/object"
 	"github.com/SirusCodes/anti-lang/src/parser"
+	"github.com/SirusCodes/anti-lang/src/vm"
 )
 
+// Engines maps the name of every execution engine to a helper running a whole program on it
+var Engines = map[string]func(input string) object.Object{
+	"tree": EvalTest,
+	"vm":   VMTest,
+}
+
+// EnvEngines are like Engines but run the program in the environment they are given
+var EnvEngines = map[string]func(input string, env *object.Environment) object.Object{
+	"tree": EvalTestEnv,
+	"vm":   VMTestEnv,
+}
+
 func EvalTest(input string) object.Object {
+	return EvalTestEnv(input, object.NewEnvironment())
+}
+
+func EvalTestEnv(input string, env *object.Environment) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	env := object.NewEnvironment()
 	return evaluator.Eval(program, env)
 }
 
+func VMTest(input string) object.Object {
+	return VMTestEnv(input, object.NewEnvironment())
+}
+
+func VMTestEnv(input string, env *object.Environment) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	return vm.New(comp.Bytecode(), env).Run()
+}
+
 func ParseInput(t *testing.T, input string) *ast.Program {
 	lexer := lexer.New(input)
 	parser := parser.New(lexer)
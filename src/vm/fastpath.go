@@ -0,0 +1,94 @@
+package vm
+
+import (
+	"math"
+
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/evaluator"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+// fastInfix applies the operators loops spend their time on to two integers
+// or two floats without going through evaluator.EvalInfix. It reports false
+// for anything else and for integer overflow, which the evaluator promotes to
+// a BigInt or reports depending on how arithmetic is checked.
+func fastInfix(op code.Opcode, left, right object.Object) (object.Object, bool) {
+	switch left := left.(type) {
+	case *object.Integer:
+		if right, ok := right.(*object.Integer); ok {
+			return integerInfix(op, left.Value, right.Value)
+		}
+	case *object.Float:
+		if right, ok := right.(*object.Float); ok {
+			return floatInfix(op, left.Value, right.Value)
+		}
+	}
+	return nil, false
+}
+
+func integerInfix(op code.Opcode, left, right int64) (object.Object, bool) {
+	switch op {
+	case code.OpAdd:
+		sum := left + right
+		if (sum > left) != (right > 0) {
+			return nil, false
+		}
+		return &object.Integer{Value: sum}, true
+	case code.OpSub:
+		difference := left - right
+		if (difference < left) != (right > 0) {
+			return nil, false
+		}
+		return &object.Integer{Value: difference}, true
+	case code.OpMul:
+		product := left * right
+		if left != 0 && (product/left != right || (left == -1 && right == math.MinInt64)) {
+			return nil, false
+		}
+		return &object.Integer{Value: product}, true
+	case code.OpEqual:
+		return nativeBool(left == right), true
+	case code.OpNotEqual:
+		return nativeBool(left != right), true
+	case code.OpLessThan:
+		return nativeBool(left < right), true
+	case code.OpLessEqual:
+		return nativeBool(left <= right), true
+	case code.OpGreaterThan:
+		return nativeBool(left > right), true
+	case code.OpGreaterEqual:
+		return nativeBool(left >= right), true
+	}
+	return nil, false
+}
+
+func floatInfix(op code.Opcode, left, right float64) (object.Object, bool) {
+	switch op {
+	case code.OpAdd:
+		return &object.Float{Value: left + right}, true
+	case code.OpSub:
+		return &object.Float{Value: left - right}, true
+	case code.OpMul:
+		return &object.Float{Value: left * right}, true
+	case code.OpEqual:
+		return nativeBool(left == right), true
+	case code.OpNotEqual:
+		return nativeBool(left != right), true
+	case code.OpLessThan:
+		return nativeBool(left < right), true
+	case code.OpLessEqual:
+		return nativeBool(left <= right), true
+	case code.OpGreaterThan:
+		return nativeBool(left > right), true
+	case code.OpGreaterEqual:
+		return nativeBool(left >= right), true
+	}
+	return nil, false
+}
+
+func nativeBool(value bool) object.Object {
+	if value {
+		return evaluator.TRUE
+	}
+	return evaluator.FALSE
+}
@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+// Frame is the execution state of a single function call
+type Frame struct {
+	cl          *object.Closure
+	locals      *object.Locals // nil for the program itself
+	ip          int
+	opStart     int // offset of the instruction being executed
+	basePointer int
+}
+
+func NewFrame(cl *object.Closure, locals *object.Locals, basePointer int) *Frame {
+	return &Frame{cl: cl, locals: locals, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+func (f *Frame) constants() []object.Object {
+	return f.cl.Fn.Constants
+}
+
+// name returns an identifier interned in the constant pool
+func (f *Frame) name(index uint32) string {
+	return f.constants()[index].(*object.String).Value
+}
@@ -0,0 +1,603 @@
+package vm
+
+import (
+	"github.com/SirusCodes/anti-lang/src/ast"
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/compiler"
+	"github.com/SirusCodes/anti-lang/src/evaluator"
+	"github.com/SirusCodes/anti-lang/src/lexer"
+	"github.com/SirusCodes/anti-lang/src/object"
+)
+
+const (
+	StackSize = 2048
+	MaxFrames = 1024
+)
+
+var infixOperators = [...]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpPow:          "**",
+	code.OpIntDiv:       "//",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpLessThan:     "<",
+	code.OpLessEqual:    "<=",
+	code.OpGreaterThan:  ">",
+	code.OpGreaterEqual: ">=",
+}
+
+var shortCircuitOperators = map[code.Opcode]string{
+	code.OpAnd:      "&&",
+	code.OpOr:       "||",
+	code.OpCoalesce: "??",
+}
+
+var prefixOperators = map[code.Opcode]string{
+	code.OpMinus: "-",
+	code.OpBang:  "!",
+}
+
+// assignOperators are the compound assignments with a fast path
+var assignOperators = map[string]code.Opcode{
+	"+=": code.OpAdd,
+	"-=": code.OpSub,
+	"*=": code.OpMul,
+}
+
+type VM struct {
+	stack []object.Object
+	sp    int // always points to the next free slot, top of stack is stack[sp-1]
+
+	frames      []*Frame
+	framesIndex int
+
+	context *object.CallContext // handed to builtins so they can call back into the vm
+	checked bool                // integer overflow is an error, see Environment.CheckedArithmetic
+
+	handlers []handler // try blocks being executed, innermost last
+}
+
+// handler is where execution resumes when an error is raised inside a try block
+type handler struct {
+	catch       int // offset of the catch block
+	framesIndex int
+	sp          int
+}
+
+// New creates a vm running the bytecode in env, pass the same env to keep
+// definitions between runs like the REPL does
+func New(bytecode *compiler.Bytecode, env *object.Environment) *VM {
+	mainFn := &object.CompiledFunction{
+		Name:         "main",
+		Instructions: bytecode.Instructions,
+		Positions:    bytecode.Positions,
+		Constants:    bytecode.Constants,
+	}
+	mainClosure := &object.Closure{Fn: mainFn, Globals: object.NewGlobals(bytecode.Globals, env)}
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = NewFrame(mainClosure, nil, 0)
+
+	vm := &VM{
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		frames:      frames,
+		framesIndex: 1,
+		checked:     env.CheckedArithmetic(),
+	}
+	vm.context = &object.CallContext{Call: vm.call, Streams: env.Streams()}
+
+	return vm
+}
+
+// Run executes the bytecode and returns the value of the program, the same
+// value evaluator.Eval would return for it
+func (vm *VM) Run() object.Object {
+	return vm.run(1)
+}
+
+// run executes instructions until the frame at depth returns, depth is 1 for
+// the program itself and deeper for functions called back from builtins
+func (vm *VM) run(depth int) object.Object {
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		frame := vm.currentFrame()
+		frame.ip++
+		frame.opStart = frame.ip
+
+		ins := frame.Instructions()
+		op := code.Opcode(ins[frame.ip])
+
+		var result object.Object
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+			result = vm.push(frame.constants()[constIndex])
+		case code.OpPop:
+			vm.pop()
+		case code.OpTrue:
+			result = vm.push(evaluator.TRUE)
+		case code.OpFalse:
+			result = vm.push(evaluator.FALSE)
+		case code.OpNull:
+			result = vm.push(evaluator.NULL)
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow, code.OpIntDiv,
+			code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpLessEqual,
+			code.OpGreaterThan, code.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			if value, ok := fastInfix(op, left, right); ok {
+				result = vm.push(value)
+			} else {
+				result = vm.pushResult(evaluator.EvalInfix(infixOperators[op], left, right, vm.checked))
+			}
+		case code.OpMinus, code.OpBang:
+			right := vm.pop()
+			result = vm.pushResult(evaluator.EvalPrefix(prefixOperators[op], right, vm.checked))
+		case code.OpJump:
+			pos := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip += 4
+
+			condition := vm.pop()
+			if !evaluator.IsTruthy(condition) {
+				frame.ip = pos - 1
+			}
+		case code.OpAnd, code.OpOr, code.OpCoalesce:
+			pos := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip += 4
+
+			if evaluator.ShortCircuits(shortCircuitOperators[op], vm.stack[vm.sp-1]) {
+				frame.ip = pos - 1
+			} else {
+				vm.pop()
+			}
+		case code.OpIter:
+			numArgs := int(code.ReadUint8(ins[frame.ip+1:]))
+			frame.ip += 1
+
+			args := make([]object.Object, numArgs)
+			copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+			vm.sp = vm.sp - numArgs
+
+			result = vm.pushResult(evaluator.NewIterator(args...))
+		case code.OpIterNext:
+			pos := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip += 4
+
+			iterator := vm.stack[vm.sp-1].(*object.Iterator)
+			if value, ok := iterator.Next(); ok {
+				result = vm.push(value)
+			} else {
+				vm.pop()
+				frame.ip = pos - 1
+			}
+		case code.OpGetGlobal:
+			index := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+
+			globals := frame.cl.Globals
+			if value := globals.Env.GetSlot(globals.Slots[index]); value != nil {
+				result = vm.push(value)
+			} else {
+				result = vm.pushResult(vm.getName(globals.Names[index], nil, globals))
+			}
+		case code.OpSetGlobal:
+			index := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+
+			globals := frame.cl.Globals
+			globals.Env.SetSlot(globals.Slots[index], vm.stack[vm.sp-1])
+		case code.OpAssignGlobal:
+			index := code.ReadUint32(ins[frame.ip+1:])
+			operatorIndex := code.ReadUint32(ins[frame.ip+5:])
+			frame.ip += 8
+
+			globals := frame.cl.Globals
+			name := globals.Names[index]
+			current := globals.Env.GetSlot(globals.Slots[index])
+			if current == nil {
+				current, _ = globals.Env.Get(name)
+			}
+
+			value := vm.assign(name, frame.name(operatorIndex), current, vm.pop())
+			if err, ok := value.(*object.Error); ok {
+				result = err
+			} else {
+				globals.Env.SetSlot(globals.Slots[index], value)
+				result = vm.push(evaluator.NULL)
+			}
+		case code.OpGetLocal:
+			slot := code.ReadUint16(ins[frame.ip+1:])
+			frame.ip += 2
+			result = vm.pushResult(vm.getLocal(frame.locals, int(slot), frame.cl.Globals))
+		case code.OpSetLocal:
+			slot := code.ReadUint16(ins[frame.ip+1:])
+			frame.ip += 2
+			frame.locals.Slots[slot] = vm.stack[vm.sp-1]
+		case code.OpAssignLocal:
+			slot := code.ReadUint16(ins[frame.ip+1:])
+			operatorIndex := code.ReadUint32(ins[frame.ip+3:])
+			frame.ip += 6
+
+			locals := frame.locals
+			name := locals.Fn.Locals[slot]
+			current := locals.Slots[slot]
+			if current == nil {
+				current, _ = vm.resolve(name, locals.Outer, frame.cl.Globals)
+			}
+
+			value := vm.assign(name, frame.name(operatorIndex), current, vm.pop())
+			if err, ok := value.(*object.Error); ok {
+				result = err
+			} else {
+				locals.Slots[slot] = value
+				result = vm.push(evaluator.NULL)
+			}
+		case code.OpGetOuter:
+			depth := code.ReadUint16(ins[frame.ip+1:])
+			slot := code.ReadUint16(ins[frame.ip+3:])
+			frame.ip += 4
+
+			locals := frame.locals
+			for i := uint16(0); i < depth; i++ {
+				locals = locals.Outer
+			}
+			result = vm.pushResult(vm.getLocal(locals, int(slot), frame.cl.Globals))
+		case code.OpImport:
+			pathIndex := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+			result = vm.pushResult(evaluator.Import(frame.name(pathIndex), frame.cl.Globals.Env))
+		case code.OpMember:
+			nameIndex := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+			result = vm.pushResult(evaluator.EvalMember(vm.pop(), frame.name(nameIndex)))
+		case code.OpArray:
+			numElements := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip += 4
+
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp = vm.sp - numElements
+
+			result = vm.push(&object.Array{Elements: elements})
+		case code.OpHash:
+			numElements := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip += 4
+
+			hash := vm.buildHash(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			result = vm.pushResult(hash)
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			result = vm.pushResult(evaluator.EvalIndex(left, index))
+		case code.OpSetIndex:
+			operatorIndex := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+
+			index := vm.pop()
+			container := vm.pop()
+			value := vm.pop()
+			result = vm.pushResult(evaluator.EvalIndexAssign(container, index, frame.name(operatorIndex), value, vm.checked))
+		case code.OpClosure:
+			constIndex := code.ReadUint32(ins[frame.ip+1:])
+			frame.ip += 4
+
+			fn := frame.constants()[constIndex].(*object.CompiledFunction)
+			result = vm.push(&object.Closure{Fn: fn, Globals: frame.cl.Globals, Outer: frame.locals})
+		case code.OpCall:
+			numArgs := code.ReadUint16(ins[frame.ip+1:])
+			frame.ip += 2
+			result = vm.callFunction(int(numArgs))
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			// a return at the top level ends the program
+			if vm.framesIndex == 1 {
+				return returnValue
+			}
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer
+			vm.dropHandlers()
+
+			if vm.framesIndex < depth {
+				return returnValue
+			}
+			result = vm.push(returnValue)
+		case code.OpTry:
+			catch := int(code.ReadUint32(ins[frame.ip+1:]))
+			frame.ip += 4
+			vm.handlers = append(vm.handlers, handler{catch: catch, framesIndex: vm.framesIndex, sp: vm.sp})
+		case code.OpEndTry:
+			if len(vm.handlers) == 0 {
+				return vm.fail(evaluator.NewError("vm: end of a try without a handler"), depth)
+			}
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		default:
+			def, err := code.Lookup(byte(op))
+			if err != nil {
+				return vm.fail(evaluator.NewError("vm: %s", err), depth)
+			}
+			return vm.fail(evaluator.NewError("vm: unhandled opcode %s", def.Name), depth)
+		}
+
+		if err, ok := result.(*object.Error); ok {
+			if vm.catch(err, depth) {
+				continue
+			}
+			return vm.fail(err, depth)
+		}
+	}
+
+	if vm.sp == 0 {
+		return nil
+	}
+	return vm.stack[vm.sp-1]
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) object.Object {
+	if vm.framesIndex >= MaxFrames {
+		return evaluator.NewKindError(evaluator.StackOverflowError, "stack overflow")
+	}
+
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// push returns an error object instead of a value when the stack is full
+func (vm *VM) push(o object.Object) object.Object {
+	if vm.sp >= StackSize {
+		return evaluator.NewKindError(evaluator.StackOverflowError, "stack overflow")
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+// pushResult pushes the result of an operation unless it is an error
+func (vm *VM) pushResult(o object.Object) object.Object {
+	if err, ok := o.(*object.Error); ok {
+		return err
+	}
+	return vm.push(o)
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+// getLocal reads a slot of locals, until it is set the name still refers to
+// the variable of the scopes around like it does in the evaluator
+func (vm *VM) getLocal(locals *object.Locals, slot int, globals *object.Globals) object.Object {
+	if value := locals.Slots[slot]; value != nil {
+		return value
+	}
+	return vm.getName(locals.Fn.Locals[slot], locals.Outer, globals)
+}
+
+// getName looks up a variable whose slot is not set by name, from locals out
+// to the globals and then the builtins
+func (vm *VM) getName(name string, locals *object.Locals, globals *object.Globals) object.Object {
+	if value, ok := vm.resolve(name, locals, globals); ok {
+		return value
+	}
+
+	if builtin, ok := evaluator.LookupBuiltin(name, globals.Env); ok {
+		return builtin
+	}
+
+	return evaluator.NewKindError(evaluator.NameError, "identifier not found: %s", name)
+}
+
+func (vm *VM) resolve(name string, locals *object.Locals, globals *object.Globals) (object.Object, bool) {
+	for l := locals; l != nil; l = l.Outer {
+		if value, ok := l.Get(name); ok {
+			return value, true
+		}
+	}
+	return globals.Env.Get(name)
+}
+
+// assign returns the new value of the variable called name assigned value
+// with operator, current is nil when it is not defined
+func (vm *VM) assign(name, operator string, current, value object.Object) object.Object {
+	if current == nil {
+		return evaluator.NewKindError(evaluator.NameError, "identifier not found: %s", name)
+	}
+	if operator == "=" {
+		return value
+	}
+
+	if op, ok := assignOperators[operator]; ok {
+		if result, ok := fastInfix(op, current, value); ok {
+			return result
+		}
+	}
+	return evaluator.AssignOperator(operator, current, value, vm.checked)
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) object.Object {
+	hash := object.NewHash()
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return evaluator.NewKindError(evaluator.KeyError, "unusable as hash key: %s", key.Type())
+		}
+
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+	}
+
+	return hash
+}
+
+func (vm *VM) callFunction(numArgs int) object.Object {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		args := make([]object.Object, numArgs)
+		copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+		vm.sp = vm.sp - numArgs - 1
+
+		result := callee.Fn(vm.context, args...)
+		if result == nil {
+			result = evaluator.NULL
+		}
+		return vm.pushResult(result)
+	default:
+		return evaluator.NewKindError(evaluator.TypeError, "not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) object.Object {
+	if numArgs < len(cl.Fn.Parameters) {
+		return evaluator.NewKindError(evaluator.ArgumentError, "wrong number of arguments. got=%d, want=%d", numArgs, len(cl.Fn.Parameters))
+	}
+
+	locals := &object.Locals{Slots: make([]object.Object, len(cl.Fn.Locals)), Fn: cl.Fn, Outer: cl.Outer}
+	copy(locals.Slots, vm.stack[vm.sp-numArgs:vm.sp-numArgs+len(cl.Fn.Parameters)])
+	vm.sp = vm.sp - numArgs - 1
+
+	return vm.pushFrame(NewFrame(cl, locals, vm.sp))
+}
+
+// Call runs fn with args to completion, functions defined by the program
+// can be called this way once Run has returned
+func (vm *VM) Call(fn object.Object, args ...object.Object) object.Object {
+	return vm.call(fn, args...)
+}
+
+// call runs fn to completion on behalf of a builtin
+func (vm *VM) call(fn object.Object, args ...object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Closure:
+		sp, framesIndex := vm.sp, vm.framesIndex
+
+		if vm.sp+len(args)+1 > StackSize {
+			return evaluator.NewKindError(evaluator.StackOverflowError, "stack overflow")
+		}
+		vm.stack[vm.sp] = fn
+		copy(vm.stack[vm.sp+1:], args)
+		vm.sp += len(args) + 1
+
+		if err := vm.callClosure(fn, len(args)); err != nil {
+			vm.sp = sp
+			return err
+		}
+
+		result := vm.run(vm.framesIndex)
+
+		// an error leaves the frames of the callback behind
+		vm.sp, vm.framesIndex = sp, framesIndex
+		return result
+	case *object.Builtin:
+		result := fn.Fn(vm.context, args...)
+		if result == nil {
+			return evaluator.NULL
+		}
+		return result
+	default:
+		return evaluator.NewKindError(evaluator.TypeError, "not a function: %s", fn.Type())
+	}
+}
+
+// fail attaches the source position and the call stack to err, mirroring
+// what the evaluator records while unwinding. Frames below depth belong to
+// the run that called a builtin and are added once the error gets there.
+func (vm *VM) fail(err *object.Error, depth int) object.Object {
+	vm.unwind(err, depth)
+	return err
+}
+
+func (vm *VM) unwind(err *object.Error, depth int) {
+	innermost := vm.framesIndex - 1
+
+	if !err.Pos.IsValid() {
+		err.Pos = vm.framePosition(innermost)
+	}
+
+	for i := innermost; i >= depth; i-- {
+		err.Stack = append(err.Stack, object.Frame{
+			Function: vm.frames[i].cl.Fn.Name,
+			Pos:      vm.framePosition(i - 1),
+		})
+	}
+}
+
+// catch resumes at the innermost try block when it was entered by this run,
+// with the frames above it unwound and the exception pushed for the handler.
+// Try blocks of the run that called a builtin get the error once the
+// builtin returns it.
+func (vm *VM) catch(err *object.Error, depth int) bool {
+	if len(vm.handlers) == 0 {
+		return false
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	if h.framesIndex < depth {
+		return false
+	}
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.unwind(err, h.framesIndex)
+
+	vm.framesIndex = h.framesIndex
+	vm.sp = h.sp
+	vm.push(&object.Exception{Err: err})
+	vm.currentFrame().ip = h.catch - 1
+
+	return true
+}
+
+// dropHandlers forgets the try blocks of frames that have returned
+func (vm *VM) dropHandlers() {
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].framesIndex > vm.framesIndex {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+func (vm *VM) framePosition(index int) lexer.Position {
+	frame := vm.frames[index]
+	return frame.cl.Fn.Positions[frame.opStart]
+}
+
+// Exec compiles program and runs it in env, compilation errors are returned
+// as error objects so callers can treat both engines alike
+func Exec(program ast.Node, env *object.Environment) object.Object {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+
+	return New(comp.Bytecode(), env).Run()
+}
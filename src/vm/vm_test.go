@@ -0,0 +1,127 @@
+package vm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SirusCodes/anti-lang/src/code"
+	"github.com/SirusCodes/anti-lang/src/compiler"
+	"github.com/SirusCodes/anti-lang/src/object"
+	"github.com/SirusCodes/anti-lang/src/utils"
+	"github.com/SirusCodes/anti-lang/src/vm"
+)
+
+func TestRecursiveFunctions(t *testing.T) {
+	input := `{n} fib func [
+	{n < 2} if [ ,n return ] else [ ,{n - 1}fib + {n - 2}fib return ]
+]
+
+,{15}fib`
+
+	testIntegerObject(t, utils.VMTest(input), 610)
+}
+
+func TestClosures(t *testing.T) {
+	input := `{a} outer func [
+	{b} inner func [ ,a + b return ]
+	,inner return
+]
+
+,{10}outer = addTen let
+,{5}addTen`
+
+	testIntegerObject(t, utils.VMTest(input), 15)
+}
+
+func TestEnvironmentIsSharedBetweenRuns(t *testing.T) {
+	env := object.NewEnvironment()
+
+	run(t, ",40 = x let\n{n} addX func [ ,n + x return ]", env)
+	evaluated := run(t, ",{2}addX", env)
+
+	testIntegerObject(t, evaluated, 42)
+}
+
+func TestWrongNumberOfArguments(t *testing.T) {
+	evaluated := utils.VMTest("{a; b} add func [ ,a + b return ]\n,{1}add")
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := "wrong number of arguments. got=1, want=2"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	evaluated := utils.VMTest("{} forever func [ ,{}forever return ]\n,{}forever")
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Message != "stack overflow" {
+		t.Errorf("wrong error message. expected=%q, got=%q", "stack overflow", errObj.Message)
+	}
+}
+
+// TestEndTryWithoutHandler checks that broken bytecode fails instead of
+// panicking
+func TestEndTryWithoutHandler(t *testing.T) {
+	bytecode := &compiler.Bytecode{Instructions: code.Make(code.OpEndTry)}
+	evaluated := vm.New(bytecode, object.NewEnvironment()).Run()
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := "vm: end of a try without a handler"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+// TestLargeProgram runs more constants than a 16-bit operand can index
+func TestLargeProgram(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 70000; i++ {
+		input.WriteString(",1\n")
+	}
+	input.WriteString(",$done$")
+
+	evaluated := utils.VMTest(input.String())
+
+	str, ok := evaluated.(*object.String)
+	if !ok || str.Value != "done" {
+		t.Errorf("expected done, got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func run(t *testing.T, input string, env *object.Environment) object.Object {
+	program := utils.ParseInput(t, input)
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	return vm.New(comp.Bytecode(), env).Run()
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
@@ -5,6 +5,10 @@ require(['vs/editor/editor.main'], function () {
     monaco.languages.register({ id: "antilang" });
 
     monaco.languages.setLanguageConfiguration("antilang", {
+        comments: {
+            lineComment: '\\\\',
+            blockComment: ['*/', '/*']
+        },
         brackets: [
             ['{', '}'],
             ['[', ']'],
@@ -26,8 +30,11 @@ require(['vs/editor/editor.main'], function () {
 
     // Register a tokens provider for the language
     monaco.languages.setMonarchTokensProvider("antilang", {
+        // identifiers can use any letter, not just ASCII ones
+        unicode: true,
+
         keywords: [
-            'let', 'func', 'while', 'return', 'null', 'if', 'else', 'true', 'false'
+            'let', 'func', 'while', 'for', 'break', 'continue', 'import', 'try', 'catch', 'return', 'null', 'if', 'else', 'true', 'false'
         ],
 
         operators: [
@@ -36,12 +43,15 @@ require(['vs/editor/editor.main'], function () {
             '*',
             '/',
             '%',
+            '**',
+            '//',
             '+=',
             '-=',
             '*=',
             '/=',
             '&&',
             '||',
+            '??',
             '==',
             '<',
             '>',
@@ -61,14 +71,14 @@ require(['vs/editor/editor.main'], function () {
 
         // we include these common regular expressions
         symbols: /[=><!~?:&|+\-*\/\^%]+/,
-        escapes: /\\(?:[abfnrtv\\"']|x[0-9A-Fa-f]{1,4}|u[0-9A-Fa-f]{4}|U[0-9A-Fa-f]{8})/,
+        escapes: /\\(?:[$\\ntr]|u\{[0-9A-Fa-f]{1,6}\})/,
 
         // The main tokenizer for our languages
         tokenizer: {
             root: [
                 // identifiers and keywords
                 [
-                    /[a-zA-Z_]\w*/,
+                    /[\p{L}_][\p{L}\p{M}_]*/u,
                     {
                         cases: {
                             '@keywords': { token: 'keyword.$0' },
@@ -94,41 +104,36 @@ require(['vs/editor/editor.main'], function () {
                 ],
 
                 // numbers
-                [/\d*\d+[eE]([\-+]?\d+)?/, 'number.float'],
-                [/\d*\.\d+([eE][\-+]?\d+)?/, 'number.float'],
-                [/\d[\d']*/, 'number'],
+                [/0[xX][\da-fA-F_]+/, 'number.hex'],
+                [/0[oO][0-7_]+/, 'number.octal'],
+                [/0[bB][01_]+/, 'number.binary'],
+                [/\d[\d_]*\.\d[\d_]*([eE][\-+]?\d[\d_]*)?/, 'number.float'],
+                [/\d[\d_]*[eE][\-+]?\d[\d_]*/, 'number.float'],
+                [/\d[\d_]*d?/, 'number'],
                 [/\d/, 'number'],
 
                 // delimiter: after number because of .\d floats
                 [/[;,.]/, 'delimiter'],
 
                 // strings
+                [/\$\$\$/, 'string', '@rawstring'],
                 [/\$([^\$\\]|\\.)*$/, 'string.invalid'], // non-teminated string
                 [/\$/, 'string', '@string'],
             ],
 
             whitespace: [
                 [/[ \t\r\n]+/, ''],
-                [/\/\*\*(?!\/)/, 'comment.doc', '@doccomment'],
-                [/\/\*/, 'comment', '@comment'],
-                [/\/\/.*$/, 'comment']
+                // comments are reversed too, */ opens and /* closes
+                [/\*\//, 'comment', '@comment'],
+                [/\\\\.*$/, 'comment']
             ],
 
             comment: [
                 [/[^\/*]+/, 'comment'],
-                // [/\/\*/, 'comment', '@push' ],    // nested comment not allowed :-(
-                // [/\/\*/,    'comment.invalid' ],    // this breaks block comments in the shape of /* //*/
-                [/\*\//, 'comment', '@pop'],
+                // nested comment not allowed
+                [/\/\*/, 'comment', '@pop'],
                 [/[\/*]/, 'comment']
             ],
-            //Identical copy of comment above, except for the addition of .doc
-            doccomment: [
-                [/[^\/*]+/, 'comment.doc'],
-                // [/\/\*/, 'comment.doc', '@push' ],    // nested comment not allowed :-(
-                [/\/\*/, 'comment.doc.invalid'],
-                [/\*\//, 'comment.doc', '@pop'],
-                [/[\/*]/, 'comment.doc']
-            ],
 
             string: [
                 [/[^\\\$]+/, 'string'],
@@ -136,6 +141,12 @@ require(['vs/editor/editor.main'], function () {
                 [/\\./, 'string.escape.invalid'],
                 [/\$/, 'string', '@pop']
             ],
+
+            rawstring: [
+                [/[^\$]+/, 'string'],
+                [/\$\$\$/, 'string', '@pop'],
+                [/\$/, 'string']
+            ],
         }
     });
 
@@ -6,10 +6,7 @@ import (
 	"fmt"
 	"syscall/js"
 
-	"github.com/SirusCodes/anti-lang/src/evaluator"
-	"github.com/SirusCodes/anti-lang/src/lexer"
-	"github.com/SirusCodes/anti-lang/src/object"
-	"github.com/SirusCodes/anti-lang/src/parser"
+	"github.com/SirusCodes/anti-lang/antilang"
 )
 
 func main() {
@@ -25,26 +22,22 @@ func main() {
 }
 
 func execute(input string) int {
-	l := lexer.New(input)
-	p := parser.New(l)
-	ast := p.ParseProgram()
+	_, err := antilang.New().Run(input)
 
-	if len(p.Errors()) != 0 {
+	switch err := err.(type) {
+	case nil:
+		return 0
+	case *antilang.SyntaxError:
 		fmt.Println("You are not AntiLang ready yet! Please fix the following errors:")
-		for _, msg := range p.Errors() {
-			fmt.Println(msg)
+		for _, d := range err.Diagnostics {
+			fmt.Println(d.String())
 		}
-		return 1
-	}
-
-	env := object.NewEnvironment()
-	resp := evaluator.Eval(ast, env)
-
-	if resp != nil && resp.Type() == object.ERROR_OBJ {
+	case *antilang.Error:
 		fmt.Println("You are not AntiLang ready yet! Please fix the following error:")
-		fmt.Println(resp.Inspect())
-		return 1
+		fmt.Print(err.Traceback())
+	default:
+		// a panic would have killed the go runtime and with it the playground
+		fmt.Println("Congratulations, you broke AntiLang itself:", err)
 	}
-
-	return 0
+	return 1
 }